@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileJar is a cookie jar kept in a JSON file, so the user ID the server
+// issues on the first call is reused by later invocations. Matching is left
+// to net/http/cookiejar, which enforces Domain, Path, Secure and expiry; the
+// file records the persistent cookies as received and replays them into the
+// jar on load. Session cookies, like in a browser, end with the invocation.
+type fileJar struct {
+	path    string
+	jar     *cookiejar.Jar
+	mu      sync.Mutex
+	entries []jarEntry
+}
+
+// jarFile is the layout of the cookie file.
+type jarFile struct {
+	Cookies []jarEntry `json:"cookies"`
+}
+
+// jarEntry is a persistent cookie and the URL that set it.
+type jarEntry struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"http_only,omitempty"`
+}
+
+func loadFileJar(path string) (*fileJar, error) {
+	inner, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	jar := &fileJar{path: path, jar: inner}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file jarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, entry := range file.Cookies {
+		u, err := url.Parse(entry.URL)
+		if err != nil || !entry.Expires.After(now) {
+			continue
+		}
+		inner.SetCookies(u, []*http.Cookie{entry.cookie()})
+		jar.entries = append(jar.entries, entry)
+	}
+	return jar, nil
+}
+
+func (j *fileJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies stores the cookies and saves the jar. A failed save only costs
+// a new user ID on the next invocation, so it is not reported.
+func (j *fileJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, cookie := range cookies {
+		entry := jarEntry{
+			URL:      origin,
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HttpOnly,
+		}
+		if entry.Path == "" || entry.Path[0] != '/' {
+			entry.Path = defaultCookiePath(u.Path)
+		}
+		if cookie.MaxAge > 0 {
+			entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		} else if cookie.MaxAge < 0 {
+			entry.Expires = time.Time{}
+		}
+
+		j.entries = removeEntry(j.entries, u.Host, entry)
+		if entry.Expires.After(now) {
+			j.entries = append(j.entries, entry)
+		}
+	}
+	_ = j.save()
+}
+
+func (j *fileJar) save() error {
+	data, err := json.Marshal(jarFile{Cookies: j.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(j.path, data, 0o600)
+}
+
+func (e jarEntry) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Domain:   e.Domain,
+		Path:     e.Path,
+		Expires:  e.Expires,
+		Secure:   e.Secure,
+		HttpOnly: e.HTTPOnly,
+	}
+}
+
+// removeEntry drops the entry a new cookie from host replaces: the one with
+// the same name, domain and path.
+func removeEntry(entries []jarEntry, host string, replacement jarEntry) []jarEntry {
+	kept := entries[:0]
+	for _, entry := range entries {
+		u, err := url.Parse(entry.URL)
+		if err == nil && u.Host == host && entry.Name == replacement.Name &&
+			strings.EqualFold(entry.Domain, replacement.Domain) && entry.Path == replacement.Path {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// defaultCookiePath is the path of a cookie set without one (RFC 6265,
+// section 5.1.4): the request path up to its last slash.
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// defaultCookieFile is the jar location under the user's config directory.
+func defaultCookieFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "url-shortener", "cookies.json")
+}
@@ -1,98 +1,248 @@
 package main
 
 import (
-	"compress/gzip"
-	"crypto/rand"
-	"encoding/base64"
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
-	"github.com/hairutdin/url-shortener/internal/app/http/middleware"
-	"github.com/hairutdin/url-shortener/internal/config"
-
-	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
+	"github.com/hairutdin/url-shortener/pkg/client"
 )
 
-func main() {
-	cfg := config.LoadConfig()
+const requestTimeout = 30 * time.Second
 
-	logger, _ := zap.NewProduction()
-	defer func() {
-		if err := logger.Sync(); err != nil {
-			fmt.Printf("logger sync failed: %v\n", err)
-		}
-	}()
-	r := gin.Default()
+const usage = `usage: client [flags] command [args]
 
-	r.Use(middleware.Logger(logger))
+Commands:
+  shorten [-alias a] [-ttl d] [-max-clicks n] URL
+                   shorten a URL
+  batch [FILE]     shorten every line of FILE or stdin, each "URL [alias]"
+  resolve CODE     print the URL a short code redirects to
+  list             list the URLs shortened by this client
+  delete CODE...   delete short codes shortened by this client
+  stats CODE       print visit statistics of a short code
 
-	r.Use(middleware.GzipMiddleware)
+Flags:`
 
-	r.POST("/", shortenURL(cfg))
-	if err := r.Run(cfg.HTTP.Address); err != nil {
-		panic(err)
-	}
+// options are the flags shared by all commands.
+type options struct {
+	server     string
+	cookieFile string
+	json       bool
+	gzip       bool
 }
 
-func shortenURL(cfg *config.Config) gin.HandlerFunc {
-	return func(c *gin.Context) {
+func main() {
+	var opts options
+	flag.StringVar(&opts.server, "server", envOr("SHORTENER_URL", "http://localhost:8080"), "shortener base URL")
+	flag.StringVar(&opts.cookieFile, "cookies", defaultCookieFile(), "file keeping the auth cookie between runs")
+	flag.BoolVar(&opts.json, "json", false, "print JSON instead of tables")
+	flag.BoolVar(&opts.gzip, "gzip", false, "gzip request bodies")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, opts, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
 
-		var bodyReader io.Reader = c.Request.Body
-		if strings.Contains(c.Request.Header.Get("Content-Encoding"), "gzip") {
-			gzipReader, err := gzip.NewReader(c.Request.Body)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gzip encoding"})
-				return
+func run(ctx context.Context, opts options, args []string) error {
+	jar, err := loadFileJar(opts.cookieFile)
+	if err != nil {
+		return fmt.Errorf("unable to read cookies: %w", err)
+	}
+	api, err := client.New(opts.server, client.Options{
+		HTTPClient: &http.Client{Jar: jar, Timeout: requestTimeout},
+		Gzip:       opts.gzip,
+	})
+	if err != nil {
+		return err
+	}
+	out := &printer{w: os.Stdout, json: opts.json}
+
+	switch args[0] {
+	case "shorten":
+		return runShorten(ctx, api, out, args[1:])
+	case "batch":
+		return runBatch(ctx, api, out, args[1:])
+	case "resolve":
+		if len(args) != 2 {
+			return errors.New("usage: resolve CODE")
+		}
+		target, err := api.Resolve(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return out.print(map[string]string{"url": target}, func(w io.Writer) { fmt.Fprintln(w, target) })
+	case "list":
+		urls, err := api.ListUserURLs(ctx)
+		if err != nil {
+			return err
+		}
+		return out.print(urls, func(w io.Writer) {
+			fmt.Fprintln(w, "SHORT URL\tORIGINAL URL")
+			for _, u := range urls {
+				fmt.Fprintf(w, "%s\t%s\n", u.ShortURL, u.OriginalURL)
 			}
-			defer gzipReader.Close()
-			bodyReader = gzipReader
+		})
+	case "delete":
+		if len(args) < 2 {
+			return errors.New("usage: delete CODE...")
 		}
-
-		var requestBody struct {
-			URL string `json:"url" binding:"required"`
+		if err := api.DeleteURLs(ctx, args[1:]); err != nil {
+			return err
 		}
-
-		decoder := json.NewDecoder(bodyReader)
-		if err := decoder.Decode(&requestBody); err != nil || requestBody.URL == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL"})
-			return
+		return out.print(map[string][]string{"queued": args[1:]}, func(w io.Writer) {
+			fmt.Fprintf(w, "queued %d short URLs for deletion\n", len(args)-1)
+		})
+	case "stats":
+		if len(args) != 2 {
+			return errors.New("usage: stats CODE")
 		}
+		stats, err := api.Stats(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return out.print(stats, func(w io.Writer) {
+			fmt.Fprintf(w, "TOTAL CLICKS\t%d\nUNIQUE VISITORS\t%d\n", stats.TotalClicks, stats.UniqueVisitors)
+			if len(stats.Daily) > 0 {
+				fmt.Fprintln(w, "\nDATE\tCLICKS")
+			}
+			for _, day := range stats.Daily {
+				fmt.Fprintf(w, "%s\t%d\n", day.Date, day.Clicks)
+			}
+		})
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runShorten(ctx context.Context, api *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	alias := fs.String("alias", "", "custom short code")
+	ttl := fs.Duration("ttl", 0, "lifetime of the short URL")
+	maxClicks := fs.Int("max-clicks", 0, "number of visits after which the short URL expires")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: shorten [-alias a] [-ttl d] [-max-clicks n] URL")
+	}
 
-		shortenedURL, err := generateShortURL()
+	shortURL, err := api.Shorten(ctx, client.ShortenRequest{
+		URL:       fs.Arg(0),
+		Alias:     *alias,
+		TTL:       int64(ttl.Round(time.Second) / time.Second),
+		MaxClicks: *maxClicks,
+	})
+	if err != nil {
+		return err
+	}
+	return out.print(map[string]string{"result": shortURL}, func(w io.Writer) { fmt.Fprintln(w, shortURL) })
+}
+
+func runBatch(ctx context.Context, api *client.Client, out *printer, args []string) error {
+	in := io.Reader(os.Stdin)
+	if len(args) > 1 {
+		return errors.New("usage: batch [FILE]")
+	}
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate short URL"})
-			return
+			return err
 		}
+		defer file.Close()
+		in = file
+	}
 
-		c.Header("Content-Type", "application/json")
-		response := gin.H{
-			"long_url":  requestBody.URL,
-			"short_url": cfg.BaseURL + shortenedURL,
+	reqs, err := readBatch(in)
+	if err != nil {
+		return err
+	}
+	if len(reqs) == 0 {
+		return errors.New("no URLs to shorten")
+	}
+
+	resp, err := api.ShortenBatch(ctx, reqs)
+	if err != nil {
+		return err
+	}
+	originals := make(map[string]string, len(reqs))
+	for _, req := range reqs {
+		originals[req.CorrelationID] = req.OriginalURL
+	}
+	return out.print(resp, func(w io.Writer) {
+		fmt.Fprintln(w, "LINE\tSHORT URL\tORIGINAL URL")
+		for _, r := range resp {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.CorrelationID, r.ShortURL, originals[r.CorrelationID])
 		}
+	})
+}
 
-		if strings.Contains(c.Request.Header.Get("Accept-Encoding"), "gzip") {
-			c.Header("Content-Encoding", "gzip")
-			gzipWriter := gzip.NewWriter(c.Writer)
-			defer gzipWriter.Close()
-			c.Writer = &middleware.GzipResponseWriter{Writer: gzipWriter, ResponseWriter: c.Writer}
+// readBatch parses lines of "URL [alias]", skipping blank lines and
+// comments. The line number is used as the correlation ID.
+func readBatch(in io.Reader) ([]client.BatchShortenRequest, error) {
+	var reqs []client.BatchShortenRequest
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected \"URL [alias]\"", line)
 		}
-		c.JSON(http.StatusCreated, response)
+		req := client.BatchShortenRequest{CorrelationID: strconv.Itoa(line), OriginalURL: fields[0]}
+		if len(fields) == 2 {
+			req.Alias = fields[1]
+		}
+		reqs = append(reqs, req)
 	}
+	return reqs, scanner.Err()
 }
 
-func generateShortURL() (string, error) {
-	byteLength := 6
-
-	randomBytes := make([]byte, byteLength)
+// printer writes results as indented JSON or as an aligned table.
+type printer struct {
+	w    io.Writer
+	json bool
+}
 
-	if _, err := rand.Read(randomBytes); err != nil {
-		return "", err
+func (p *printer) print(v any, table func(io.Writer)) error {
+	if p.json {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
 	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
 
-	shortURL := base64.RawURLEncoding.EncodeToString(randomBytes)
-	return shortURL, nil
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
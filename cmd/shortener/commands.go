@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/repository/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `usage: shortener [flags] [command]
+
+Without a command the HTTP and gRPC servers are started.
+
+Commands:
+  migrate up      apply all pending database migrations
+  migrate down    roll back the most recently applied migration
+  migrate status  list migrations and whether they are applied
+  import [-format csv|jsonl] [-chunk n] FILE
+                  store the links of FILE ("-" for stdin), one per row of
+                  url, optional alias and optional owner
+  export [-format csv|jsonl] [FILE]
+                  write all live links to FILE, stdout by default
+  copy-storage [-page n] [-verify-only] SOURCE DEST
+                  copy every record between storages, each given as a
+                  Postgres DSN or a file path; run again to resume`
+
+// runCommand executes a maintenance subcommand given after the flags.
+func runCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, cfg, args[1:])
+	case "import":
+		return runImport(ctx, cfg, args[1:])
+	case "export":
+		return runExport(ctx, cfg, args[1:])
+	case "copy-storage":
+		return runCopyStorage(ctx, cfg, args[1:])
+	case "help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(usage)
+	}
+	if cfg.DatabaseDSN == "" {
+		return errors.New("migrate requires a database DSN (-d or DATABASE_DSN)")
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("unable to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	migrator, err := migrations.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("no migrations to roll back")
+			return nil
+		}
+		fmt.Printf("rolled back %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+	return nil
+}
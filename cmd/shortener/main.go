@@ -2,16 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	grpcserver "github.com/hairutdin/url-shortener/internal/app/grpc/server"
 	"github.com/hairutdin/url-shortener/internal/app/http/handlers"
 	"github.com/hairutdin/url-shortener/internal/box"
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
 	"github.com/hairutdin/url-shortener/internal/service"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	// policyWatchInterval is how often the URL policy file is checked for changes.
+	policyWatchInterval = 5 * time.Second
+	// rateLimitEvictInterval is how often refilled rate limit buckets are dropped.
+	rateLimitEvictInterval = time.Minute
 )
 
 // @title			URL Shortener Service
@@ -19,9 +37,22 @@ import (
 // @description	A service for shortening URLs
 // @in				header
 func main() {
+	cfg, err := box.LoadConfig()
+	if err != nil {
+		log.Fatalf("unable to load config: %v", err)
+	}
+	if args := flag.Args(); len(args) > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		if err := runCommand(ctx, cfg, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	envBox, err := box.New()
 	if err != nil {
-		envBox.Logger.Fatal("unable to initialize box", zap.Error(err))
+		log.Fatalf("unable to initialize box: %v", err)
 	}
 
 	_ = zap.ReplaceGlobals(envBox.Logger)
@@ -32,11 +63,46 @@ func main() {
 		}
 	}(envBox.Logger)
 
-	urlService := service.NewURLService(envBox.Storage, envBox.Logger, envBox.Config.BaseURL)
+	var (
+		urlPolicy  *policy.Policy
+		hostPolicy service.HostPolicy
+	)
+	if envBox.Config.URLPolicyFile != "" {
+		urlPolicy, err = policy.New(envBox.Config.URLPolicyFile, envBox.Logger, policyWatchInterval)
+		if err != nil {
+			envBox.Logger.Fatal("failed to load URL policy", zap.Error(err))
+		}
+		defer urlPolicy.Close()
+		hostPolicy = urlPolicy
+		go reloadOnHangup(urlPolicy, envBox.Logger)
+	}
+
+	urlService := service.NewURLService(
+		envBox.Storage,
+		envBox.Logger,
+		envBox.Config.BaseURL,
+		envBox.Codes,
+		service.URLNormalizer{
+			AllowedSchemes: envBox.Config.AllowedURLSchemes(),
+			AllowPrivate:   envBox.Config.URLAllowPrivate,
+			SortQuery:      envBox.Config.URLSortQuery,
+		},
+		hostPolicy,
+	)
+	var janitor *service.Janitor
+	if envBox.Config.JanitorInterval > 0 {
+		janitor = service.NewJanitor(envBox.Storage, envBox.Logger, envBox.Config.JanitorInterval)
+	}
+
 	baseHandler := handlers.NewBaseHandler(urlService, envBox.Logger, envBox.Config)
-	httpHandlers := handlers.SetupRouter(envBox.Config, envBox.Logger, baseHandler)
+	limiter := ratelimit.NewMemoryLimiter(rateLimitEvictInterval)
+	defer limiter.Close()
+	httpHandlers := handlers.SetupRouter(envBox.Config, envBox.Logger, baseHandler, envBox.Metrics, limiter)
 
-	envBox.Logger.Info("starting server", zap.String("address", envBox.Config.HTTP.Address))
+	envBox.Logger.Info("starting server",
+		zap.String("address", envBox.Config.HTTP.Address),
+		zap.Bool("https", envBox.Config.HTTP.EnableHTTPS),
+	)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -51,11 +117,39 @@ func main() {
 	}
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if envBox.Config.HTTP.EnableHTTPS {
+			certFile, keyFile, tlsErr := tlsFiles(envBox.Config)
+			if tlsErr != nil {
+				envBox.Logger.Fatal("failed to prepare TLS certificate", zap.Error(tlsErr))
+			}
+			err = srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			envBox.Logger.Fatal("failed to start server", zap.Error(err))
 		}
 	}()
 
+	var grpcSrv *grpc.Server
+	if envBox.Config.GRPCAddress != "" {
+		listener, err := net.Listen("tcp", envBox.Config.GRPCAddress)
+		if err != nil {
+			envBox.Logger.Fatal("failed to listen for gRPC", zap.Error(err))
+		}
+
+		grpcHandler := grpcserver.NewShortenerServer(urlService, envBox.Logger, envBox.Config)
+		grpcSrv = grpcserver.SetupServer(envBox.Config, envBox.Logger, grpcHandler, limiter)
+
+		envBox.Logger.Info("starting gRPC server", zap.String("address", envBox.Config.GRPCAddress))
+		go func() {
+			if err := grpcSrv.Serve(listener); err != nil {
+				envBox.Logger.Fatal("failed to start gRPC server", zap.Error(err))
+			}
+		}()
+	}
+
 	envBox.Logger.Info("server started")
 
 	<-ctx.Done()
@@ -68,5 +162,51 @@ func main() {
 		envBox.Logger.Fatal("failed to shutdown server", zap.Error(err))
 	}
 
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
+
+	urlService.Close()
+	if janitor != nil {
+		janitor.Close()
+	}
+
+	if err := envBox.Storage.Close(); err != nil {
+		envBox.Logger.Error("failed to close storage", zap.Error(err))
+	}
+
 	envBox.Logger.Info("server stopped")
 }
+
+// tlsFiles returns the configured certificate and key, falling back to a
+// self-signed pair cached in a directory private to the current user.
+func tlsFiles(cfg *config.Config) (string, string, error) {
+	if cfg.HTTP.CertFile != "" && cfg.HTTP.KeyFile != "" {
+		return cfg.HTTP.CertFile, cfg.HTTP.KeyFile, nil
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(cfg.HTTP.Address); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+	dir := cfg.HTTP.CertCacheDir
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", "", fmt.Errorf("no directory for the self-signed certificate, set tls_cache_dir: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "url-shortener", "tls")
+	}
+	return lib.EnsureSelfSignedCert(dir, hosts)
+}
+
+// reloadOnHangup re-reads the URL policy file whenever the process receives SIGHUP.
+func reloadOnHangup(p *policy.Policy, logger *zap.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := p.Reload(); err != nil {
+			logger.Error("failed to reload URL policy", zap.Error(err))
+		}
+	}
+}
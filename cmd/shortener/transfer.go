@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hairutdin/url-shortener/internal/box"
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"github.com/hairutdin/url-shortener/internal/transfer"
+)
+
+func runImport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "input format, csv or jsonl; guessed from the file name if empty")
+	chunk := fs.Int("chunk", transfer.DefaultChunkSize, "rows stored per batch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+
+	env, err := openTransferStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(env)
+
+	in, closeIn, err := openInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	if *format == "" {
+		if *format, err = transfer.FormatFromPath(fs.Arg(0)); err != nil {
+			return err
+		}
+	}
+	rows, err := transfer.NewRowReader(in, *format)
+	if err != nil {
+		return err
+	}
+
+	importer := &transfer.Importer{
+		Storage: env.Storage,
+		Codes:   env.Codes,
+		URLs: service.URLNormalizer{
+			AllowedSchemes: cfg.AllowedURLSchemes(),
+			AllowPrivate:   cfg.URLAllowPrivate,
+			SortQuery:      cfg.URLSortQuery,
+		},
+		Logger:    env.Logger,
+		ChunkSize: *chunk,
+		OnFailure: func(err *transfer.RowError) {
+			fmt.Fprintln(os.Stderr, err)
+		},
+	}
+	if cfg.URLPolicyFile != "" {
+		urlPolicy, err := policy.New(cfg.URLPolicyFile, env.Logger, 0)
+		if err != nil {
+			return fmt.Errorf("failed to load URL policy: %w", err)
+		}
+		defer urlPolicy.Close()
+		importer.Policy = urlPolicy
+	}
+	report, err := importer.Import(ctx, rows)
+	fmt.Fprintf(os.Stderr, "read %d rows: %d imported, %d failed\n", report.Read, report.Imported, report.Failed)
+	return err
+}
+
+func runExport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "output format, csv or jsonl; guessed from the file name if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New(usage)
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		if path == "" || path == "-" {
+			*format = transfer.FormatJSONL
+		} else {
+			var err error
+			if *format, err = transfer.FormatFromPath(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	env, err := openTransferStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(env)
+
+	out := io.Writer(os.Stdout)
+	if path != "" && path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	rows, err := transfer.NewRowWriter(out, *format)
+	if err != nil {
+		return err
+	}
+	written, err := transfer.Export(ctx, env.Storage, rows, transfer.DefaultPageSize)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d rows\n", written)
+	return nil
+}
+
+func runCopyStorage(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("copy-storage", flag.ContinueOnError)
+	pageSize := fs.Int("page", transfer.DefaultPageSize, "records read and written per batch")
+	verifyOnly := fs.Bool("verify-only", false, "only compare the storages")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || fs.Arg(0) == fs.Arg(1) {
+		return errors.New(usage)
+	}
+
+	source, err := box.OpenStorage(box.StorageConfig(cfg, fs.Arg(0)))
+	if err != nil {
+		return fmt.Errorf("unable to open source: %w", err)
+	}
+	defer closeQuietly(source)
+	destination, err := box.OpenStorage(box.StorageConfig(cfg, fs.Arg(1)))
+	if err != nil {
+		return fmt.Errorf("unable to open destination: %w", err)
+	}
+	defer closeQuietly(destination)
+
+	migration := &transfer.Migration{
+		Source:      source,
+		Destination: destination,
+		PageSize:    *pageSize,
+		OnProgress: func(report transfer.CopyReport) {
+			fmt.Fprintf(os.Stderr, "\rcopied %d, already present %d, clicks %d", report.Copied, report.Skipped,
+				report.Clicks)
+		},
+	}
+	if !*verifyOnly {
+		report, err := migration.Copy(ctx)
+		fmt.Fprintf(os.Stderr, "\rcopied %d, already present %d, clicks %d, counter %d\n", report.Copied,
+			report.Skipped, report.Clicks, report.Counter)
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := migration.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "verified %d records and %d clicks, checksum %s\n", report.Count, report.Clicks,
+		report.SourceSum)
+	return nil
+}
+
+// openTransferStorage opens the configured storage, which has to outlive
+// the process for an import or export to make sense.
+func openTransferStorage(cfg *config.Config) (*box.Env, error) {
+	if cfg.StorageType == "memory" {
+		return nil, errors.New("import and export need file or database storage (-f or -d)")
+	}
+	return box.New()
+}
+
+func closeStorage(env *box.Env) {
+	closeQuietly(env.Storage)
+}
+
+func closeQuietly(storage repository.Storage) {
+	if err := storage.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to close storage:", err)
+	}
+}
+
+func openInput(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { _ = file.Close() }, nil
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// UserIDMetadataKey carries the signed user token, in the same format as
+	// the HTTP user_id cookie.
+	UserIDMetadataKey = "user-id"
+	// RequestIDMetadataKey carries the request ID, like the HTTP X-Request-ID header.
+	RequestIDMetadataKey = "x-request-id"
+
+	// rateLimitByUser matches middleware.RateLimitByUser.
+	rateLimitByUser = "user"
+)
+
+type userIDKey struct{}
+
+type authInvalidKey struct{}
+
+type userIssuedKey struct{}
+
+// LoggingInterceptor takes the request ID from the metadata, generating one
+// when it is missing, and returns it in the response header. The call
+// context gets a logger with the request and user IDs, and every unary call
+// is logged with the same fields as the HTTP request logger.
+func LoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		var requestID, userAgent string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(RequestIDMetadataKey); len(ids) > 0 {
+				requestID = ids[0]
+			}
+			if agents := md.Get("user-agent"); len(agents) > 0 {
+				userAgent = agents[0]
+			}
+		}
+		if !lib.ValidRequestID(requestID) {
+			requestID = lib.GenerateUUID()
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID)); err != nil {
+			return nil, err
+		}
+
+		fields := []zap.Field{zap.String("request_id", requestID)}
+		if userID := UserID(ctx); userID != "" {
+			fields = append(fields, zap.String("user_id", userID))
+		}
+		requestLogger := logger.With(fields...)
+		resp, err := handler(lib.ContextWithLogger(ctx, requestLogger), req)
+
+		var clientIP string
+		if p, ok := peer.FromContext(ctx); ok {
+			clientIP = p.Addr.String()
+		}
+		requestLogger.Info("Request info",
+			zap.String("method", info.FullMethod),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("status", status.Code(err).String()),
+			zap.String("client_ip", clientIP),
+			zap.String("user_agent", userAgent),
+		)
+		return resp, err
+	}
+}
+
+// AuthInterceptor identifies the caller by the signed user token in the
+// request metadata, issuing a new one in the response header when it is
+// missing or fails verification.
+func AuthInterceptor(secret string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if tokens := md.Get(UserIDMetadataKey); len(tokens) > 0 {
+				if userID, ok := lib.VerifyUserID(tokens[0], secret); ok {
+					return handler(context.WithValue(ctx, userIDKey{}, userID), req)
+				}
+				ctx = context.WithValue(ctx, authInvalidKey{}, true)
+			}
+		}
+
+		userID := lib.GenerateUUID()
+		if err := grpc.SetHeader(ctx, metadata.Pairs(UserIDMetadataKey, lib.SignUserID(userID, secret))); err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, userIssuedKey{}, true)
+		return handler(context.WithValue(ctx, userIDKey{}, userID), req)
+	}
+}
+
+// MethodLimit is the rate limit of a gRPC method and the route class whose
+// buckets it shares with the HTTP API.
+type MethodLimit struct {
+	Class string
+	Limit ratelimit.Limit
+}
+
+// RateLimitInterceptor takes a token from the caller's bucket for the
+// method's class and fails the call with ResourceExhausted when it is empty.
+// Callers are keyed like by the HTTP RateLimit middleware, so both APIs draw
+// from the same buckets: by user ID when keyBy is "user" and the call carried
+// a valid token, otherwise by client IP, read from the x-real-ip or
+// x-forwarded-for metadata only on connections from the trusted proxies.
+// Methods without a limit and calls the limiter fails on are let through.
+func RateLimitInterceptor(
+	limiter ratelimit.Limiter,
+	limits map[string]MethodLimit,
+	keyBy string,
+	proxies []netip.Prefix,
+	trustForwardedFor bool,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		limit, ok := limits[info.FullMethod]
+		if !ok || !limit.Limit.Enabled() {
+			return handler(ctx, req)
+		}
+
+		key := limit.Class + ":"
+		if userID := UserID(ctx); keyBy == rateLimitByUser && userID != "" && !userIssued(ctx) {
+			key += "user:" + userID
+		} else {
+			key += "ip:" + clientIP(ctx, proxies, trustForwardedFor)
+		}
+
+		result, err := limiter.Allow(ctx, key, limit.Limit)
+		if err != nil {
+			lib.LoggerFromContext(ctx, zap.L()).Error("rate limiter failed", zap.Error(err))
+			return handler(ctx, req)
+		}
+		if !result.Allowed {
+			retryAfter := strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+			return nil, status.Error(codes.ResourceExhausted, "Too many requests")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// clientIP returns the caller's IP, believing the client IP metadata only
+// from the trusted proxies.
+func clientIP(ctx context.Context, proxies []netip.Prefix, trustForwardedFor bool) string {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return lib.ClientIP(remoteAddr, header, proxies, trustForwardedFor)
+}
+
+// UserID returns the user ID assigned to the call by AuthInterceptor.
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+func authInvalid(ctx context.Context) bool {
+	invalid, _ := ctx.Value(authInvalidKey{}).(bool)
+	return invalid
+}
+
+func userIssued(ctx context.Context) bool {
+	issued, _ := ctx.Value(userIssuedKey{}).(bool)
+	return issued
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/app/grpc/pb"
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/models"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ShortenerServer struct {
+	pb.UnimplementedShortenerServer
+
+	service service.IURLService
+	logger  *zap.Logger
+	cfg     *config.Config
+}
+
+func NewShortenerServer(service service.IURLService, logger *zap.Logger, cfg *config.Config) *ShortenerServer {
+	return &ShortenerServer{
+		service: service,
+		logger:  logger,
+		cfg:     cfg,
+	}
+}
+
+// SetupServer returns the gRPC server. limiter, which the HTTP router should
+// share, applies the configured rate limits; nil turns them off.
+func SetupServer(
+	cfg *config.Config,
+	logger *zap.Logger,
+	handler *ShortenerServer,
+	limiter ratelimit.Limiter,
+) *grpc.Server {
+	// Auth runs first so the request logger can include the user ID, and
+	// calls refused by the rate limit are still logged.
+	interceptors := []grpc.UnaryServerInterceptor{
+		AuthInterceptor(cfg.AuthSecret),
+		LoggingInterceptor(logger),
+	}
+	if limiter != nil {
+		createLimit := MethodLimit{Class: "create",
+			Limit: ratelimit.PerMinute(cfg.RateLimit.CreatePerMinute, cfg.RateLimit.CreateBurst)}
+		redirectLimit := MethodLimit{Class: "redirect",
+			Limit: ratelimit.PerMinute(cfg.RateLimit.RedirectPerMinute, cfg.RateLimit.RedirectBurst)}
+		interceptors = append(interceptors, RateLimitInterceptor(limiter, map[string]MethodLimit{
+			pb.Shortener_Shorten_FullMethodName:      createLimit,
+			pb.Shortener_ShortenBatch_FullMethodName: createLimit,
+			pb.Shortener_Resolve_FullMethodName:      redirectLimit,
+		}, cfg.RateLimit.Key, cfg.RateLimit.ProxyPrefixes(), cfg.TrustForwardedFor))
+	}
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	pb.RegisterShortenerServer(srv, handler)
+	return srv
+}
+
+func (s *ShortenerServer) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	opts := service.ShortenOptions{
+		Alias:     req.GetAlias(),
+		TTL:       time.Duration(req.GetTtlSeconds()) * time.Second,
+		MaxClicks: int(req.GetMaxClicks()),
+	}
+	if req.GetExpiresAt() != nil {
+		expiresAt := req.GetExpiresAt().AsTime()
+		opts.ExpiresAt = &expiresAt
+	}
+
+	var (
+		shortURL string
+		err      error
+	)
+	if opts == (service.ShortenOptions{}) {
+		shortURL, err = s.service.ShortenURL(ctx, req.GetUrl(), UserID(ctx))
+	} else {
+		shortURL, err = s.service.ShortenURLWithOptions(ctx, req.GetUrl(), UserID(ctx), opts)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateURL) {
+			return nil, status.Errorf(codes.AlreadyExists, "URL already exists: %s", s.cfg.BaseURL+"/"+shortURL)
+		}
+		return nil, s.toStatus(ctx, err, "Failed to generate short URL")
+	}
+
+	return &pb.ShortenResponse{ShortUrl: s.cfg.BaseURL + "/" + shortURL}, nil
+}
+
+func (s *ShortenerServer) ShortenBatch(
+	ctx context.Context,
+	req *pb.ShortenBatchRequest,
+) (*pb.ShortenBatchResponse, error) {
+	if len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Empty batch not allowed")
+	}
+
+	batchRequest := make([]models.BatchShortenRequest, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		batchRequest = append(batchRequest, models.BatchShortenRequest{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   item.GetOriginalUrl(),
+			Alias:         item.GetAlias(),
+		})
+	}
+
+	batchResponse, err := s.service.ShortenBatchURLs(ctx, batchRequest, UserID(ctx))
+	if err != nil {
+		return nil, s.toStatus(ctx, err, "Failed to create batch URLs")
+	}
+
+	resp := &pb.ShortenBatchResponse{Items: make([]*pb.BatchResult, 0, len(batchResponse))}
+	for _, item := range batchResponse {
+		resp.Items = append(resp.Items, &pb.BatchResult{
+			CorrelationId: item.CorrelationID,
+			ShortUrl:      item.ShortURL,
+		})
+	}
+	return resp, nil
+}
+
+func (s *ShortenerServer) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	originalURL, err := s.service.GetOriginalURL(ctx, req.GetShortId())
+	if err != nil {
+		return nil, s.toStatus(ctx, err, "Failed to resolve URL")
+	}
+
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	ip := clientIP(ctx, s.cfg.RateLimit.ProxyPrefixes(), s.cfg.TrustForwardedFor)
+	s.service.RecordClick(ctx, req.GetShortId(), "", userAgent, ip)
+
+	return &pb.ResolveResponse{OriginalUrl: originalURL}, nil
+}
+
+func (s *ShortenerServer) ListUserURLs(
+	ctx context.Context,
+	_ *pb.ListUserURLsRequest,
+) (*pb.ListUserURLsResponse, error) {
+	if authInvalid(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	userURLs, err := s.service.GetUserURLs(ctx, UserID(ctx))
+	if err != nil {
+		return nil, s.toStatus(ctx, err, "Failed to get user URLs")
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(userURLs))}
+	for _, userURL := range userURLs {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    userURL.ShortURL,
+			OriginalUrl: userURL.OriginalURL,
+		})
+	}
+	return resp, nil
+}
+
+func (s *ShortenerServer) DeleteURLs(ctx context.Context, req *pb.DeleteURLsRequest) (*pb.DeleteURLsResponse, error) {
+	if authInvalid(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	if len(req.GetShortIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Empty batch not allowed")
+	}
+
+	if err := s.service.DeleteURLs(ctx, UserID(ctx), req.GetShortIds()); err != nil {
+		return nil, s.toStatus(ctx, err, "Failed to delete URLs")
+	}
+	return &pb.DeleteURLsResponse{}, nil
+}
+
+func (s *ShortenerServer) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.service.Ping(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, "Database connection failed")
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// toStatus maps service and storage errors to gRPC status codes. Unknown
+// errors are logged and reported as Internal with the given message.
+func (s *ShortenerServer) toStatus(ctx context.Context, err error, internalMsg string) error {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateURL), errors.Is(err, repository.ErrShortURLTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrInvalidAlias), errors.Is(err, service.ErrInvalidExpiry),
+		errors.Is(err, service.ErrInvalidURL):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, policy.ErrBlocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrDeleted), errors.Is(err, repository.ErrExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrDeleterStopped), errors.Is(err, service.ErrNoFreeCode),
+		errors.Is(err, lib.ErrCodeSpaceExhausted):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	lib.LoggerFromContext(ctx, s.logger).Error(internalMsg, zap.Error(err))
+	return status.Error(codes.Internal, internalMsg)
+}
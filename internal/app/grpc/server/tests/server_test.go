@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hairutdin/url-shortener/internal/app/grpc/pb"
+	"github.com/hairutdin/url-shortener/internal/app/grpc/server"
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service/mocks"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupTestClient(t *testing.T, mockService *mocks.MockIURLService) pb.ShortenerClient {
+	t.Helper()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	return setupLimitedTestClient(t, mockService, cfg, nil)
+}
+
+func setupLimitedTestClient(
+	t *testing.T,
+	mockService *mocks.MockIURLService,
+	cfg *config.Config,
+	limiter ratelimit.Limiter,
+) pb.ShortenerClient {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	srv := server.SetupServer(cfg, logger, server.NewShortenerServer(mockService, logger, cfg), limiter)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestShorten(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).Return("short123", nil)
+
+	client := setupTestClient(t, mockService)
+
+	var header metadata.MD
+	resp, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetShortUrl() != "http://localhost:8080/short123" {
+		t.Errorf("Expected short URL 'http://localhost:8080/short123', got %s", resp.GetShortUrl())
+	}
+	if len(header.Get(server.UserIDMetadataKey)) == 0 {
+		t.Errorf("Expected user token to be issued in response header")
+	}
+}
+
+func TestShorten_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).Return("short123", nil)
+
+	limiter := ratelimit.NewMemoryLimiter(time.Minute)
+	defer limiter.Close()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	cfg.RateLimit = config.RateLimitConfig{Key: "ip", CreatePerMinute: 1, CreateBurst: 1}
+	client := setupLimitedTestClient(t, mockService, cfg, limiter)
+
+	req := &pb.ShortenRequest{Url: "https://example.com"}
+	if _, err := client.Shorten(context.Background(), req); err != nil {
+		t.Fatalf("Expected the first call to pass, got %v", err)
+	}
+
+	// The connection is not from a trusted proxy, so a reported IP is ignored.
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-real-ip", "203.0.113.7")
+	var header metadata.MD
+	_, err := client.Shorten(ctx, req, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if len(header.Get("retry-after")) == 0 {
+		t.Error("Expected a retry-after header")
+	}
+}
+
+func TestShorten_Duplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().
+		ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).
+		Return("short123", repository.ErrDuplicateURL)
+
+	client := setupTestClient(t, mockService)
+
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+}
+
+func TestResolve_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetOriginalURL(gomock.Any(), "missing").Return("", repository.ErrNotFound)
+
+	client := setupTestClient(t, mockService)
+
+	_, err := client.Resolve(context.Background(), &pb.ResolveRequest{ShortId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestListUserURLs_TamperedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	client := setupTestClient(t, mockService)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), server.UserIDMetadataKey, "user-1.deadbeef")
+	_, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"github.com/gin-gonic/gin"
 	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/lib"
 	"github.com/hairutdin/url-shortener/internal/service"
 	"go.uber.org/zap"
 )
@@ -19,3 +21,8 @@ func NewBaseHandler(service service.IURLService, logger *zap.Logger, cfg *config
 		cfg:     cfg,
 	}
 }
+
+// log returns the request-scoped logger set up by the RequestID middleware.
+func (h *BaseHandler) log(c *gin.Context) *zap.Logger {
+	return lib.LoggerFromContext(c.Request.Context(), h.logger)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"go.uber.org/zap"
+)
+
+// statusFor maps service and storage errors to HTTP status codes. Unknown
+// errors, such as a storage outage, map to 500.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAlias), errors.Is(err, service.ErrInvalidExpiry),
+		errors.Is(err, service.ErrInvalidURL):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrDuplicateURL), errors.Is(err, repository.ErrShortURLTaken):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrDeleted), errors.Is(err, repository.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrDeleterStopped), errors.Is(err, service.ErrNoFreeCode),
+		errors.Is(err, lib.ErrCodeSpaceExhausted):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError responds with the status matching err. Server-side failures are
+// logged and reported with internalMsg so storage details do not leak;
+// rejected URLs also carry a machine-readable reason or the blocking rule.
+func (h *BaseHandler) writeError(c *gin.Context, err error, internalMsg string) {
+	code := statusFor(err)
+	if code >= http.StatusInternalServerError {
+		h.log(c).Error(internalMsg, zap.Error(err))
+		c.JSON(code, gin.H{"error": internalMsg})
+		return
+	}
+
+	var invalidURL *service.InvalidURLError
+	if errors.As(err, &invalidURL) {
+		c.JSON(code, gin.H{"error": err.Error(), "reason": invalidURL.Reason})
+		return
+	}
+
+	var blocked *policy.BlockedError
+	if errors.As(err, &blocked) {
+		c.JSON(code, gin.H{"error": err.Error(), "rule": blocked.Rule})
+		return
+	}
+
+	message := err.Error()
+	if errors.Is(err, repository.ErrShortURLTaken) {
+		message = "Alias is already taken"
+	}
+	c.JSON(code, gin.H{"error": message})
+}
@@ -4,20 +4,62 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/hairutdin/url-shortener/internal/app/http/middleware"
 	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/metrics"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
 	"go.uber.org/zap"
 )
 
-func SetupRouter(cfg *config.Config, logger *zap.Logger, handler *BaseHandler) *gin.Engine {
+// SetupRouter builds the HTTP router. When m is not nil, requests are
+// instrumented and the metrics are served on /metrics; when limiter is not
+// nil, shorten and redirect routes are rate limited per client.
+func SetupRouter(
+	cfg *config.Config,
+	logger *zap.Logger,
+	handler *BaseHandler,
+	m *metrics.Metrics,
+	limiter ratelimit.Limiter,
+) *gin.Engine {
 	r := gin.Default()
+	// Client IPs come from RealIP, which believes only the configured proxies.
+	_ = r.SetTrustedProxies(nil)
+	r.Use(middleware.RealIP(cfg.RateLimit.ProxyPrefixes(), cfg.TrustForwardedFor))
 
+	if m != nil {
+		r.Use(middleware.Metrics(m))
+		r.GET("/metrics", gin.WrapH(m.Handler()))
+	}
+	r.Use(middleware.RequestID(logger))
 	r.Use(middleware.Logger(logger))
 	r.Use(middleware.GzipMiddleware)
+	r.Use(middleware.Auth(cfg.AuthSecret, cfg.HTTP.EnableHTTPS))
 
-	r.POST("/", handler.HandleShortenPost)
-	r.POST("/api/shorten", handler.HandleShortenPost)
-	r.POST("/api/shorten/batch", handler.handleBatchShortenPost)
-	r.GET("/:id", handler.handleGet)
+	createLimit := rateLimit(cfg, limiter, "create",
+		ratelimit.PerMinute(cfg.RateLimit.CreatePerMinute, cfg.RateLimit.CreateBurst))
+	redirectLimit := rateLimit(cfg, limiter, "redirect",
+		ratelimit.PerMinute(cfg.RateLimit.RedirectPerMinute, cfg.RateLimit.RedirectBurst))
+
+	r.POST("/", createLimit, handler.HandleShortenPost)
+	r.POST("/api/shorten", createLimit, handler.HandleShortenPost)
+	r.POST("/api/shorten/batch", createLimit, handler.handleBatchShortenPost)
+	r.GET("/:id", redirectLimit, handler.handleGet)
+	r.GET("/api/urls/:id/stats", handler.handleGetURLStats)
 	r.GET("/ping", handler.handlePing)
 
+	user := r.Group("/api/user", middleware.RequireAuth())
+	user.GET("/urls", handler.handleGetUserURLs)
+	user.DELETE("/urls", handler.handleDeleteUserURLs)
+
+	internal := r.Group("/api/internal", middleware.TrustedSubnet(cfg.TrustedSubnet, cfg.TrustForwardedFor))
+	internal.GET("/stats", handler.handleGetInternalStats)
+
 	return r
 }
+
+// rateLimit returns the middleware limiting one route class, or a no-op when
+// limiting is off.
+func rateLimit(cfg *config.Config, limiter ratelimit.Limiter, class string, limit ratelimit.Limit) gin.HandlerFunc {
+	if limiter == nil || !limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.RateLimit(limiter, class, limit, cfg.RateLimit.Key)
+}
@@ -3,31 +3,47 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/app/http/middleware"
 	"github.com/hairutdin/url-shortener/internal/models"
 	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
 	"go.uber.org/zap"
 )
 
 func (h *BaseHandler) HandleShortenPost(c *gin.Context) {
-	var requestBody struct {
-		URL string `json:"url" binding:"required"`
-	}
+	var requestBody models.ShortenRequest
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		h.logger.Warn("Invalid request format", zap.Error(err))
+		h.log(c).Warn("Invalid request format", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	shortURL, err := h.service.ShortenURL(requestBody.URL)
+	opts := service.ShortenOptions{
+		Alias:     requestBody.Alias,
+		ExpiresAt: requestBody.ExpiresAt,
+		TTL:       time.Duration(requestBody.TTL) * time.Second,
+		MaxClicks: requestBody.MaxClicks,
+	}
+
+	var (
+		shortURL string
+		err      error
+	)
+	if opts == (service.ShortenOptions{}) {
+		shortURL, err = h.service.ShortenURL(c.Request.Context(), requestBody.URL, middleware.UserID(c))
+	} else {
+		shortURL, err = h.service.ShortenURLWithOptions(c.Request.Context(), requestBody.URL, middleware.UserID(c), opts)
+	}
 	if err != nil {
-		if errors.Is(err, repository.ErrDuplicateURL) {
-			c.JSON(http.StatusConflict, gin.H{"short_url": h.cfg.BaseURL + "/" + shortURL})
+		var duplicate *repository.DuplicateURLError
+		if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, gin.H{"short_url": h.cfg.BaseURL + "/" + duplicate.ShortURL})
 			return
 		}
-		h.logger.Error("Failed to generate short URL", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate short URL"})
+		h.writeError(c, err, "Failed to generate short URL")
 		return
 	}
 
@@ -46,9 +62,9 @@ func (h *BaseHandler) handleBatchShortenPost(c *gin.Context) {
 		return
 	}
 
-	batchResponse, err := h.service.ShortenBatchURLs(batchRequest)
+	batchResponse, err := h.service.ShortenBatchURLs(c.Request.Context(), batchRequest, middleware.UserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create batch URLs"})
+		h.writeError(c, err, "Failed to create batch URLs")
 		return
 	}
 
@@ -57,17 +73,71 @@ func (h *BaseHandler) handleBatchShortenPost(c *gin.Context) {
 
 func (h *BaseHandler) handleGet(c *gin.Context) {
 	shortURL := c.Param("id")
-	originalURL, err := h.service.GetOriginalURL(shortURL)
+	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), shortURL)
 	if err != nil {
-		h.logger.Error("URL not found", zap.String("shortURL", shortURL), zap.Error(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		h.writeError(c, err, "Failed to resolve URL")
 		return
 	}
+	h.service.RecordClick(c.Request.Context(), shortURL, c.Request.Referer(), c.Request.UserAgent(), middleware.ClientIP(c))
 	c.Redirect(http.StatusTemporaryRedirect, originalURL)
 }
 
+func (h *BaseHandler) handleGetURLStats(c *gin.Context) {
+	shortURL := c.Param("id")
+	stats, err := h.service.GetURLStats(c.Request.Context(), shortURL)
+	if err != nil {
+		h.writeError(c, err, "Failed to get URL stats")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *BaseHandler) handleGetInternalStats(c *gin.Context) {
+	stats, err := h.service.GetInternalStats(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to get stats")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *BaseHandler) handleGetUserURLs(c *gin.Context) {
+	userURLs, err := h.service.GetUserURLs(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		h.writeError(c, err, "Failed to get user URLs")
+		return
+	}
+
+	if len(userURLs) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, userURLs)
+}
+
+func (h *BaseHandler) handleDeleteUserURLs(c *gin.Context) {
+	var shortURLs []string
+	if err := c.ShouldBindJSON(&shortURLs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if len(shortURLs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Empty batch not allowed"})
+		return
+	}
+
+	if err := h.service.DeleteURLs(c.Request.Context(), middleware.UserID(c), shortURLs); err != nil {
+		h.writeError(c, err, "Failed to delete URLs")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
 func (h *BaseHandler) handlePing(c *gin.Context) {
-	if err := h.service.Ping(); err != nil {
+	if err := h.service.Ping(c.Request.Context()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "Database connection failed"})
 		return
 	}
@@ -1,7 +1,10 @@
 package tests
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,9 +13,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/hairutdin/url-shortener/internal/app/http/handlers"
+	"github.com/hairutdin/url-shortener/internal/app/http/middleware"
 	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/metrics"
+	"github.com/hairutdin/url-shortener/internal/models"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
 	"github.com/hairutdin/url-shortener/internal/service/mocks"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func setupTestHandler(mockService *mocks.MockIURLService) *handlers.BaseHandler {
@@ -25,7 +36,7 @@ func TestHandleShortenPost(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockService := mocks.NewMockIURLService(ctrl)
-	mockService.EXPECT().ShortenURL("https://example.com").Return("short123", nil)
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", "").Return("short123", nil)
 
 	router := gin.Default()
 	handler := setupTestHandler(mockService)
@@ -51,3 +62,590 @@ func TestHandleShortenPost(t *testing.T) {
 		t.Errorf("Expected short URL 'http://localhost:8080/short123', got %s", response["result"])
 	}
 }
+
+func setupTestRouter(mockService *mocks.MockIURLService) *gin.Engine {
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	handler := handlers.NewBaseHandler(mockService, logger, cfg)
+	return handlers.SetupRouter(cfg, logger, handler, nil, nil)
+}
+
+func TestHandleGetUserURLs_Empty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetUserURLs(gomock.Any(), gomock.Any()).Return([]models.UserURLResponse{}, nil)
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", recorder.Code)
+	}
+
+	if len(recorder.Result().Cookies()) == 0 {
+		t.Errorf("Expected auth cookie to be issued")
+	}
+}
+
+func TestAuthCookie_SecureWithHTTPS(t *testing.T) {
+	for _, https := range []bool{false, true} {
+		ctrl := gomock.NewController(t)
+		mockService := mocks.NewMockIURLService(ctrl)
+		mockService.EXPECT().GetUserURLs(gomock.Any(), gomock.Any()).Return([]models.UserURLResponse{}, nil)
+
+		logger, _ := zap.NewDevelopment()
+		cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+		cfg.HTTP.EnableHTTPS = https
+		router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), nil, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/user/urls", nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		cookies := recorder.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Secure != https {
+			t.Errorf("Expected one cookie with Secure=%v when HTTPS is %v, got %+v", https, https, cookies)
+		}
+		ctrl.Finish()
+	}
+}
+
+func TestHandleGetUserURLs_SameUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	router := setupTestRouter(mockService)
+
+	var userID string
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, id string) (string, error) {
+			userID = id
+			return "short123", nil
+		})
+
+	body := `{"url": "https://example.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatalf("Expected auth cookie to be issued")
+	}
+
+	mockService.EXPECT().
+		GetUserURLs(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) ([]models.UserURLResponse, error) {
+			if id != userID {
+				t.Errorf("Expected user ID %s, got %s", userID, id)
+			}
+			return []models.UserURLResponse{
+				{ShortURL: "http://localhost:8080/short123", OriginalURL: "https://example.com"},
+			}, nil
+		})
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	var response []models.UserURLResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+
+	if len(response) != 1 || response[0].OriginalURL != "https://example.com" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestHandleGetUserURLs_TamperedCookie(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(&http.Cookie{Name: middleware.UserIDCookie, Value: "user-1.deadbeef"})
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", recorder.Code)
+	}
+}
+
+func TestHandleDeleteUserURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().DeleteURLs(gomock.Any(), gomock.Any(), []string{"short1", "short2"}).Return(nil)
+
+	router := setupTestRouter(mockService)
+
+	body := `["short1", "short2"]`
+	req, _ := http.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", recorder.Code)
+	}
+}
+
+func TestHandleGet_Gone(t *testing.T) {
+	for _, serviceErr := range []error{repository.ErrDeleted, repository.ErrExpired} {
+		t.Run(serviceErr.Error(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mocks.NewMockIURLService(ctrl)
+			mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("", serviceErr)
+
+			router := setupTestRouter(mockService)
+
+			req, _ := http.NewRequest(http.MethodGet, "/short123", nil)
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusGone {
+				t.Errorf("Expected status 410, got %d", recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGet_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{"not found", repository.ErrNotFound, http.StatusNotFound},
+		{"blocked host", &policy.BlockedError{Host: "phish.example", Rule: "deny phish.example"}, http.StatusForbidden},
+		{"storage outage", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mocks.NewMockIURLService(ctrl)
+			mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("", tt.serviceErr)
+
+			router := setupTestRouter(mockService)
+
+			req, _ := http.NewRequest(http.MethodGet, "/short123", nil)
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, recorder.Code)
+			}
+			if strings.Contains(recorder.Body.String(), "connection refused") {
+				t.Errorf("Expected storage details to stay hidden, got %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleShortenPost_Duplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().
+		ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).
+		Return("existing", &repository.DuplicateURLError{ShortURL: "existing"})
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), `"short_url":"http://localhost:8080/existing"`) {
+		t.Errorf("Expected existing short URL in body, got %s", recorder.Body.String())
+	}
+}
+
+func TestHandleShortenPost_InvalidURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().
+		ShortenURL(gomock.Any(), "javascript:alert(1)", gomock.Any()).
+		Return("", &service.InvalidURLError{Reason: service.ReasonUnsupportedScheme, Detail: "scheme is not allowed"})
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "javascript:alert(1)"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), `"reason":"unsupported_scheme"`) {
+		t.Errorf("Expected reason in body, got %s", recorder.Body.String())
+	}
+}
+
+func TestHandleShortenPost_NoFreeCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().
+		ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).
+		Return("", fmt.Errorf("%w after 5 attempts", service.ErrNoFreeCode))
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", recorder.Code)
+	}
+	if strings.Contains(recorder.Body.String(), "Alias") {
+		t.Errorf("Expected no alias error for a generated code, got %s", recorder.Body.String())
+	}
+}
+
+func TestHandleGet_RecordsClick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("https://example.com", nil)
+	// The client is not a trusted proxy, so its forged headers are ignored.
+	mockService.EXPECT().RecordClick(gomock.Any(), "short123", "https://ref.example", "test-agent", "198.51.100.9")
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodGet, "/short123", nil)
+	req.RemoteAddr = "198.51.100.9:41000"
+	req.Header.Set("Referer", "https://ref.example")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Real-IP", "203.0.113.5")
+	req.Header.Set("X-Forwarded-For", "203.0.113.6")
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusTemporaryRedirect {
+		t.Errorf("Expected status 307, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Location") != "https://example.com" {
+		t.Errorf("Expected redirect to https://example.com, got %s", recorder.Header().Get("Location"))
+	}
+}
+
+func TestHandleGet_RecordsProxiedClientIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("https://example.com", nil)
+	mockService.EXPECT().RecordClick(gomock.Any(), "short123", "", "", "203.0.113.5")
+
+	logger := zap.NewNop()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	cfg.RateLimit.TrustedProxies = "10.0.0.0/8"
+	router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), nil, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/short123", nil)
+	req.RemoteAddr = "10.1.2.3:41000"
+	req.Header.Set("X-Real-IP", "203.0.113.5")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusTemporaryRedirect {
+		t.Errorf("Expected status 307, got %d", recorder.Code)
+	}
+}
+
+func TestHandleGetURLStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetURLStats(gomock.Any(), "short123").Return(models.URLStatsResponse{
+		TotalClicks:    2,
+		UniqueVisitors: 1,
+		Daily:          []models.DailyClicks{{Date: "2024-10-01", Clicks: 2}},
+	}, nil)
+
+	router := setupTestRouter(mockService)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/urls/short123/stats", nil)
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	var response models.URLStatsResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+	if response.TotalClicks != 2 || len(response.Daily) != 1 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestHandleShortenPost_Alias(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{name: "created", serviceErr: nil, wantStatus: http.StatusCreated},
+		{name: "invalid", serviceErr: service.ErrInvalidAlias, wantStatus: http.StatusBadRequest},
+		{name: "taken", serviceErr: repository.ErrShortURLTaken, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mocks.NewMockIURLService(ctrl)
+			mockService.EXPECT().
+				ShortenURLWithOptions(gomock.Any(), "https://example.com", gomock.Any(), service.ShortenOptions{Alias: "my-link"}).
+				Return("my-link", tt.serviceErr)
+
+			router := setupTestRouter(mockService)
+
+			body := `{"url": "https://example.com", "alias": "my-link"}`
+			req, _ := http.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetInternalStats_TrustedSubnet(t *testing.T) {
+	tests := []struct {
+		name       string
+		subnet     string
+		trustXFF   bool
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"inside subnet", "192.168.1.0/24", false, map[string]string{"X-Real-IP": "192.168.1.7"}, http.StatusOK},
+		{"outside subnet", "192.168.1.0/24", false, map[string]string{"X-Real-IP": "10.0.0.1"}, http.StatusForbidden},
+		{"no client IP", "192.168.1.0/24", false, nil, http.StatusForbidden},
+		{"subnet not configured", "", false, map[string]string{"X-Real-IP": "192.168.1.7"}, http.StatusForbidden},
+		{"forwarded for ignored", "192.168.1.0/24", false,
+			map[string]string{"X-Forwarded-For": "192.168.1.7"}, http.StatusForbidden},
+		{"forwarded for trusted", "192.168.1.0/24", true,
+			map[string]string{"X-Forwarded-For": "10.0.0.1, 192.168.1.7"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mocks.NewMockIURLService(ctrl)
+			if tt.wantStatus == http.StatusOK {
+				mockService.EXPECT().GetInternalStats(gomock.Any()).Return(models.InternalStatsResponse{URLs: 3, Users: 2}, nil)
+			}
+
+			logger, _ := zap.NewDevelopment()
+			cfg := &config.Config{
+				BaseURL:           "http://localhost:8080",
+				AuthSecret:        "test-secret",
+				TrustedSubnet:     tt.subnet,
+				TrustForwardedFor: tt.trustXFF,
+			}
+			router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), nil, nil)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, recorder.Code)
+			}
+			if tt.wantStatus == http.StatusOK && recorder.Body.String() != `{"urls":3,"users":2}` {
+				t.Errorf("Unexpected body: %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("https://example.com", nil)
+	mockService.EXPECT().RecordClick(gomock.Any(), "short123", gomock.Any(), gomock.Any(), gomock.Any())
+	mockService.EXPECT().
+		ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).
+		Return("existing", &repository.DuplicateURLError{ShortURL: "existing"})
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), metrics.New(), nil)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/short123", nil))
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`shortener_http_requests_total{method="GET",route="/:id",status="307"} 1`,
+		`shortener_http_request_duration_seconds_count{method="POST",route="/api/shorten",status="409"} 1`,
+		`shortener_redirects_total{status="307"} 1`,
+		`shortener_shorten_total{outcome="conflict"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("https://example.com", nil).Times(2)
+	mockService.EXPECT().RecordClick(gomock.Any(), "short123", gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/short123", nil)
+	req.Header.Set(middleware.RequestIDHeader, "client-id-1")
+	req.Header.Set("User-Agent", "test-agent")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if got := recorder.Header().Get(middleware.RequestIDHeader); got != "client-id-1" {
+		t.Errorf("Expected the client request ID to be echoed, got %q", got)
+	}
+
+	entries := logs.FilterMessage("Request info").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one access log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "client-id-1" || fields["user_agent"] != "test-agent" || fields["user_id"] == "" {
+		t.Errorf("Expected request ID, user agent and user ID in the access log, got %v", fields)
+	}
+
+	// IDs with control characters are replaced by a generated one.
+	req = httptest.NewRequest(http.MethodGet, "/short123", nil)
+	req.Header.Set(middleware.RequestIDHeader, "bad id\n")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if got := recorder.Header().Get(middleware.RequestIDHeader); got == "" || got == "bad id\n" {
+		t.Errorf("Expected a generated request ID, got %q", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockIURLService(ctrl)
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).Return("short123", nil).Times(2)
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AuthSecret: "test-secret"}
+	cfg.RateLimit = config.RateLimitConfig{Key: "ip", CreatePerMinute: 1, CreateBurst: 2, TrustedProxies: "10.0.0.0/8"}
+	limiter := ratelimit.NewMemoryLimiter(0)
+	defer limiter.Close()
+	router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(mockService, logger, cfg), nil, limiter)
+
+	shorten := func(remoteAddr, realIP string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Real-IP", realIP)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := shorten("198.51.100.7:1234", "203.0.113.1")
+	if first.Code != http.StatusCreated || first.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("Expected 201 with one request remaining, got %d %v", first.Code, first.Header())
+	}
+	shorten("198.51.100.7:1234", "203.0.113.2")
+
+	// A client that is not a trusted proxy cannot get a new bucket by changing X-Real-IP.
+	limited := shorten("198.51.100.7:1234", "203.0.113.3")
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", limited.Code)
+	}
+	if limited.Header().Get("Retry-After") == "" || limited.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("Expected Retry-After and X-RateLimit-Limit headers, got %v", limited.Header())
+	}
+
+	// Redirect limits are disabled in this config, and clients behind the trusted proxy have their own bucket.
+	mockService.EXPECT().ShortenURL(gomock.Any(), "https://example.com", gomock.Any()).Return("short123", nil)
+	if other := shorten("10.0.0.1:443", "203.0.113.3"); other.Code != http.StatusCreated {
+		t.Errorf("Expected the proxied client to be allowed, got %d", other.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"go.uber.org/zap"
+)
+
+const (
+	UserIDCookie = "user_id"
+
+	userIDKey      = "userID"
+	authInvalidKey = "authInvalid"
+	userIssuedKey  = "userIssued"
+
+	cookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// Auth identifies the caller by an HMAC-signed user ID cookie. Requests
+// without a valid cookie get a freshly issued user ID; a cookie that fails
+// signature verification is remembered so RequireAuth can reject it. secure
+// marks issued cookies HTTPS-only.
+func Auth(secret string, secure bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookie, err := c.Cookie(UserIDCookie); err == nil {
+			if userID, ok := lib.VerifyUserID(cookie, secret); ok {
+				setUserID(c, userID)
+				c.Next()
+				return
+			}
+			c.Set(authInvalidKey, true)
+		}
+
+		userID := lib.GenerateUUID()
+		c.SetCookie(UserIDCookie, lib.SignUserID(userID, secret), cookieMaxAge, "/", "", secure, true)
+		c.Set(userIssuedKey, true)
+		setUserID(c, userID)
+		c.Next()
+	}
+}
+
+// setUserID records the caller and adds it to the request-scoped logger.
+func setUserID(c *gin.Context, userID string) {
+	c.Set(userIDKey, userID)
+	withLogFields(c, zap.L(), zap.String("user_id", userID))
+}
+
+// RequireAuth rejects requests that arrived with a tampered auth cookie.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(authInvalidKey) || UserID(c) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserID returns the user ID assigned to the request by Auth.
+func UserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
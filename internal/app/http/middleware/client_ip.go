@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
+)
+
+const clientIPKey = "clientIP"
+
+// RealIP resolves the client IP once per request for ClientIP. X-Real-IP (or
+// X-Forwarded-For when trustForwardedFor is set) is only believed on
+// connections from the trusted proxies; anyone else could forge it to skew
+// rate limits, visitor statistics and logs.
+func RealIP(proxies []netip.Prefix, trustForwardedFor bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(clientIPKey, lib.ClientIP(c.Request.RemoteAddr, c.Request.Header.Get, proxies, trustForwardedFor))
+		c.Next()
+	}
+}
+
+// ClientIP returns the client IP resolved by RealIP, or the connection's
+// address when RealIP did not run.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(c.Request.RemoteAddr); err == nil {
+		return host
+	}
+	return c.Request.RemoteAddr
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/metrics"
+)
+
+const (
+	redirectRoute = "/:id"
+	// unmatchedRoute labels requests that matched no route, so arbitrary
+	// paths do not create new series.
+	unmatchedRoute = "unmatched"
+)
+
+// shortenRoutes are the routes whose responses count as shorten outcomes.
+var shortenRoutes = map[string]bool{
+	"/":                  true,
+	"/api/shorten":       true,
+	"/api/shorten/batch": true,
+}
+
+// Metrics records request counts and latencies per route and status, plus
+// redirect and shorten outcomes.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := c.Writer.Status()
+		m.ObserveRequest(route, c.Request.Method, status, time.Since(start))
+
+		switch {
+		case route == redirectRoute:
+			m.ObserveRedirect(status)
+		case shortenRoutes[route] && c.Request.Method == http.MethodPost:
+			m.ObserveShorten(status)
+		}
+	}
+}
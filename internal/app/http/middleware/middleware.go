@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +43,8 @@ func (w *GzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Logger writes an access log line per request through the request-scoped
+// logger, so it carries the request and user IDs when they are known.
 func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -51,12 +54,14 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 		status := c.Writer.Status()
 		size := c.Writer.Size()
 
-		logger.Info("Request info",
+		lib.LoggerFromContext(c.Request.Context(), logger).Info("Request info",
 			zap.String("uri", c.Request.RequestURI),
 			zap.String("method", c.Request.Method),
 			zap.Duration("duration", duration),
 			zap.Int("status", status),
 			zap.Int("size", size),
+			zap.String("client_ip", ClientIP(c)),
+			zap.String("user_agent", c.Request.UserAgent()),
 		)
 	}
 }
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"go.uber.org/zap"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	requestIDKey = "requestID"
+)
+
+// RequestID takes the request ID from the X-Request-ID header, generating
+// one when it is missing or unusable, and echoes it in the response. The
+// request context gets a logger that includes the ID.
+func RequestID(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !lib.ValidRequestID(id) {
+			id = lib.GenerateUUID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		withLogFields(c, logger, zap.String("request_id", id))
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned to the request by RequestID.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// withLogFields adds fields to the request-scoped logger, starting from
+// fallback when the request has none yet.
+func withLogFields(c *gin.Context, fallback *zap.Logger, fields ...zap.Field) {
+	ctx := c.Request.Context()
+	logger := lib.LoggerFromContext(ctx, fallback).With(fields...)
+	c.Request = c.Request.WithContext(lib.ContextWithLogger(ctx, logger))
+}
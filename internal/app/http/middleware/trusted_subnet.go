@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/lib"
+)
+
+// TrustedSubnet lets through only requests whose client IP falls inside the
+// given CIDR. The IP is taken from X-Real-IP, or from the last X-Forwarded-For
+// hop when trustForwardedFor is set. An empty or invalid CIDR denies everyone.
+func TrustedSubnet(cidr string, trustForwardedFor bool) gin.HandlerFunc {
+	_, subnet, _ := net.ParseCIDR(cidr)
+
+	return func(c *gin.Context) {
+		ip := net.ParseIP(lib.ForwardedIP(c.Request.Header.Get, trustForwardedFor))
+		if subnet == nil || ip == nil || !subnet.Contains(ip) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,10 +1,16 @@
 package box
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/metrics"
 	"github.com/hairutdin/url-shortener/internal/repository"
 
 	"github.com/joho/godotenv"
@@ -13,6 +19,8 @@ import (
 
 const (
 	envDev = "develop"
+
+	connectTimeout = 10 * time.Second
 )
 
 var (
@@ -24,14 +32,18 @@ type Env struct {
 	Config  *config.Config
 	Logger  *zap.Logger
 	Storage repository.Storage
+	Codes   lib.CodeGenerator
+	Metrics *metrics.Metrics
 }
 
 func New() (*Env, error) {
 	var err error
 	once.Do(func() {
-		_ = godotenv.Load("config/.env")
-
-		cfg := config.LoadConfig()
+		cfg, cfgErr := LoadConfig()
+		if cfgErr != nil {
+			err = fmt.Errorf("failed to load config: %w", cfgErr)
+			return
+		}
 
 		logger, loggerErr := SetupLogger(cfg.Env)
 		if loggerErr != nil {
@@ -39,16 +51,26 @@ func New() (*Env, error) {
 			return
 		}
 
-		storage, storageErr := initializeStorage(cfg)
+		m := metrics.New()
+		storage, storageErr := initializeStorage(cfg, m)
 		if storageErr != nil {
 			err = fmt.Errorf("failed to initialize storage: %w", storageErr)
 			return
 		}
 
+		codes, codesErr := initializeCodeGenerator(cfg, storage)
+		if codesErr != nil {
+			_ = storage.Close()
+			err = fmt.Errorf("failed to initialize short code generator: %w", codesErr)
+			return
+		}
+
 		instance = &Env{
 			Config:  cfg,
 			Logger:  logger,
 			Storage: storage,
+			Codes:   codes,
+			Metrics: m,
 		}
 	})
 
@@ -58,6 +80,13 @@ func New() (*Env, error) {
 	return instance, nil
 }
 
+// LoadConfig loads config/.env, if present, into the environment and then
+// builds the configuration.
+func LoadConfig() (*config.Config, error) {
+	_ = godotenv.Load("config/.env")
+	return config.LoadConfig()
+}
+
 func SetupLogger(env string) (*zap.Logger, error) {
 	if env == envDev {
 		return zap.NewDevelopment()
@@ -65,13 +94,87 @@ func SetupLogger(env string) (*zap.Logger, error) {
 	return zap.NewProduction()
 }
 
-func initializeStorage(cfg *config.Config) (repository.Storage, error) {
+// initializeStorage opens the configured backend, instruments it and puts
+// the cache in front, so cache hits are not counted as storage calls.
+func initializeStorage(cfg *config.Config, m *metrics.Metrics) (repository.Storage, error) {
+	backend, err := OpenStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	storage := repository.Storage(metrics.InstrumentStorage(backend, cfg.StorageType, m))
+	if cfg.CacheSize == 0 {
+		return storage, nil
+	}
+
+	cache := repository.NewCachedStorage(storage, repository.CacheOptions{
+		Size:        cfg.CacheSize,
+		TTL:         cfg.CacheTTL,
+		NegativeTTL: cfg.CacheNegativeTTL,
+	})
+	if err := m.RegisterCache(cache); err != nil {
+		_ = cache.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+// OpenStorage opens the backend selected by cfg without the cache and
+// instrumentation the server adds.
+func OpenStorage(cfg *config.Config) (repository.Storage, error) {
 	switch cfg.StorageType {
 	case "postgres":
-		return repository.NewPostgresStorage(cfg.DatabaseDSN)
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+		return repository.NewPostgresStorage(ctx, cfg.DatabaseDSN, cfg.DatabaseMaxConns, cfg.DatabaseAutoMigrate)
 	case "file":
-		return repository.NewFileStorage(cfg.FileStoragePath)
+		return repository.NewFileStorage(cfg.FileStoragePath, repository.FileStorageOptions{
+			Sync:            repository.SyncPolicy(cfg.FileSync),
+			CompactInterval: cfg.FileCompactInterval,
+		})
 	default:
 		return repository.NewInMemoryStorage(), nil
 	}
 }
+
+// StorageConfig returns a copy of cfg whose storage is the given location:
+// a Postgres URL or keyword/value DSN, or a file path.
+func StorageConfig(cfg *config.Config, location string) *config.Config {
+	storageCfg := *cfg
+	storageCfg.DatabaseDSN = ""
+	storageCfg.FileStoragePath = ""
+	if isPostgresDSN(location) {
+		storageCfg.DatabaseDSN = location
+	} else {
+		storageCfg.FileStoragePath = location
+	}
+	storageCfg.SelectStorage()
+	return &storageCfg
+}
+
+// keywordDSN matches the start of a libpq keyword/value DSN such as
+// "host=localhost dbname=shortener".
+var keywordDSN = regexp.MustCompile(`^\s*[a-z_]+\s*=`)
+
+func isPostgresDSN(location string) bool {
+	return strings.HasPrefix(location, "postgres://") || strings.HasPrefix(location, "postgresql://") ||
+		keywordDSN.MatchString(location)
+}
+
+// initializeCodeGenerator builds the configured generator. The counter
+// strategy draws its values from the storage, so codes handed out before a
+// restart, including ones since deleted or purged, are not issued again.
+func initializeCodeGenerator(cfg *config.Config, storage repository.Storage) (lib.CodeGenerator, error) {
+	return lib.NewCodeGenerator(
+		cfg.ShortCodeStrategy, cfg.ShortCodeLength, []byte(cfg.AuthSecret), storageCounter{storage})
+}
+
+// storageCounter reserves counter values in the storage.
+type storageCounter struct {
+	storage repository.Storage
+}
+
+func (c storageCounter) Reserve(n uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	return c.storage.ReserveCounter(ctx, n)
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/box"
+	"github.com/hairutdin/url-shortener/internal/config"
+)
+
+func TestStorageConfig(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{location: "postgres://user@localhost/shortener", want: "postgres"},
+		{location: "postgresql://localhost/shortener", want: "postgres"},
+		{location: "host=localhost dbname=shortener sslmode=disable", want: "postgres"},
+		{location: "/tmp/short-url-db.json", want: "file"},
+		{location: "urls.jsonl", want: "file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			cfg := box.StorageConfig(&config.Config{DatabaseDSN: "postgres://default"}, tt.location)
+			if cfg.StorageType != tt.want {
+				t.Errorf("Expected %s storage, got %s", tt.want, cfg.StorageType)
+			}
+		})
+	}
+}
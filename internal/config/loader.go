@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyDefaults sets every field that has an envDefault tag.
+func applyDefaults(cfg *Config) error {
+	return walkFields(reflect.ValueOf(cfg).Elem(), func(field reflect.Value, tag reflect.StructTag) error {
+		def, ok := tag.Lookup("envDefault")
+		if !ok {
+			return nil
+		}
+		return setFromString(field, def)
+	})
+}
+
+// applyEnv overrides fields with the variables named in their env tags.
+// Malformed values are collected and reported together.
+func applyEnv(cfg *Config) error {
+	var errs []error
+	_ = walkFields(reflect.ValueOf(cfg).Elem(), func(field reflect.Value, tag reflect.StructTag) error {
+		name := tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := setFromString(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", name, err))
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// applyFile overrides fields with the values present in the JSON file.
+// Durations may be strings such as "10s" or nanosecond numbers; unknown keys
+// are rejected to catch typos.
+func applyFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := decodeFile(reflect.ValueOf(cfg).Elem(), data, ""); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func decodeFile(v reflect.Value, data []byte, prefix string) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(raw))
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		known[name] = true
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		var err error
+		switch {
+		case field.Kind() == reflect.Struct:
+			err = decodeFile(field, value, prefix+name+".")
+		case field.Type() == durationType:
+			err = decodeDuration(field, value)
+		default:
+			err = json.Unmarshal(value, field.Addr().Interface())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: %w", prefix, name, err))
+		}
+	}
+
+	for name := range raw {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("%s%s: unknown field", prefix, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func decodeDuration(field reflect.Value, value json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return setFromString(field, s)
+	}
+	var n int64
+	if err := json.Unmarshal(value, &n); err != nil {
+		return errors.New("duration must be a string like \"10s\" or a number of nanoseconds")
+	}
+	field.SetInt(n)
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	name := f.Tag.Get("json")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// walkFields calls fn for every non-struct field, descending into nested structs.
+func walkFields(v reflect.Value, fn func(field reflect.Value, tag reflect.StructTag) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && field.Type() != durationType {
+			if err := walkFields(field, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, t.Field(i).Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setFromString(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.CanInt():
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
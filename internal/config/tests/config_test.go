@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/config"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("AUTH_SECRET", "config-test-secret-0123456789")
+	os.Exit(m.Run())
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"http": {"address": "127.0.0.1:9000", "read_timeout": "3s", "write_timeout": 2000000000},
+		"base_url": "http://file.example/",
+		"file_storage_path": "",
+		"database_dsn": ""
+	}`)
+	t.Setenv("CONFIG", path)
+	t.Setenv("BASE_URL", "http://env.example/")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "42s")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.HTTP.Address != "127.0.0.1:9000" {
+		t.Errorf("Expected address from file, got %q", cfg.HTTP.Address)
+	}
+	if cfg.BaseURL != "http://env.example/" {
+		t.Errorf("Expected env to override file, got %q", cfg.BaseURL)
+	}
+	if cfg.HTTP.ReadTimeout != 3*time.Second || cfg.HTTP.WriteTimeout != 2*time.Second {
+		t.Errorf("Expected timeouts from file, got %s and %s", cfg.HTTP.ReadTimeout, cfg.HTTP.WriteTimeout)
+	}
+	if cfg.HTTP.IdleTimeout != 42*time.Second {
+		t.Errorf("Expected idle timeout from env, got %s", cfg.HTTP.IdleTimeout)
+	}
+	if cfg.HTTP.HeaderTimeout != 5*time.Second {
+		t.Errorf("Expected default header timeout, got %s", cfg.HTTP.HeaderTimeout)
+	}
+	if cfg.StorageType != "memory" {
+		t.Errorf("Expected memory storage, got %q", cfg.StorageType)
+	}
+}
+
+func TestLoadConfig_AggregatesErrors(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"http": {"address": "no-port", "tls_cert_file": "cert.pem"},
+		"base_url": "ftp://example.com/"
+	}`)
+	t.Setenv("CONFIG", path)
+	t.Setenv("JANITOR_INTERVAL", "-1m")
+	t.Setenv("AUTH_SECRET", "url-shortener-secret")
+
+	_, err := config.LoadConfig()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, want := range []string{"server address", "base URL", "auth secret", "janitor interval", "TLS certificate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_AuthSecretRequired(t *testing.T) {
+	t.Setenv("AUTH_SECRET", "")
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "auth secret must be set") {
+		t.Errorf("Expected a missing secret to be rejected, got %v", err)
+	}
+
+	t.Setenv("AUTH_SECRET", "short")
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "at least 16") {
+		t.Errorf("Expected a short secret to be rejected, got %v", err)
+	}
+}
+
+func TestLoadConfig_ShortCodeLengthPerStrategy(t *testing.T) {
+	t.Setenv("SHORT_CODE_LENGTH", "12")
+	if _, err := config.LoadConfig(); err != nil {
+		t.Errorf("Expected 12 characters to suit random codes, got %v", err)
+	}
+
+	t.Setenv("SHORT_CODE_STRATEGY", "counter")
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "must be 4 to 10") {
+		t.Errorf("Expected counter codes to be limited to 10 characters, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnknownFileKey(t *testing.T) {
+	t.Setenv("CONFIG", writeConfigFile(t, `{"http": {"adress": "localhost:8080"}}`))
+
+	_, err := config.LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "http.adress") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
+
+// setFlag registers the configuration flags, if needed, and sets one as if
+// it had been given on the command line. Flags stay set for later tests.
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := flag.Set(name, value); err != nil {
+		t.Fatalf("Failed to set -%s: %v", name, err)
+	}
+}
+
+func TestLoadConfig_MaxConnsFlag(t *testing.T) {
+	t.Setenv("DATABASE_MAX_CONNS", "7")
+	setFlag(t, "max-conns", "3")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.DatabaseMaxConns != 3 {
+		t.Errorf("Expected the flag to override env, got %d", cfg.DatabaseMaxConns)
+	}
+}
+
+func TestLoadConfig_AutoMigrate(t *testing.T) {
+	t.Setenv("DATABASE_AUTO_MIGRATE", "false")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.DatabaseAutoMigrate {
+		t.Error("Expected env to disable migrations")
+	}
+
+	setFlag(t, "auto-migrate", "true")
+	if cfg, err = config.LoadConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cfg.DatabaseAutoMigrate {
+		t.Error("Expected the flag to override env")
+	}
+}
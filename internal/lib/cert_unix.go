@@ -0,0 +1,22 @@
+//go:build unix
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPrivate requires the directory to belong to the current user and to be
+// closed to everyone else.
+func checkPrivate(dir string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("certificate cache %s is owned by another user", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("certificate cache %s is accessible to other users; expected mode 0700", dir)
+	}
+	return nil
+}
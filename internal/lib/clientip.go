@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedIP returns the client IP a proxy reported: X-Real-IP, or the last
+// X-Forwarded-For hop when trustForwardedFor is set. header reads a request
+// header by name, from HTTP headers or gRPC metadata.
+func ForwardedIP(header func(name string) string, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		// Our proxy appends the address it saw, so earlier hops may be forged.
+		if forwarded := header("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	return strings.TrimSpace(header("X-Real-IP"))
+}
+
+// ClientIP returns the IP of the peer at remoteAddr or, for a peer among the
+// trusted proxies, the client IP it reported. Headers of other peers are
+// ignored, since anyone can send them.
+func ClientIP(remoteAddr string, header func(name string) string, proxies []netip.Prefix, trustForwardedFor bool) string {
+	remote, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		remote = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(remote)
+	if err != nil {
+		return remote
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(addr.Unmap()) {
+			if ip := ForwardedIP(header, trustForwardedFor); ip != "" {
+				return ip
+			}
+			break
+		}
+	}
+	return remote
+}
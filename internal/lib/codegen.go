@@ -0,0 +1,215 @@
+package lib
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+)
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Short-code generation strategies accepted by NewCodeGenerator.
+const (
+	CodeStrategyRandom  = "random"
+	CodeStrategyCounter = "counter"
+	CodeStrategyHash    = "hash"
+)
+
+const (
+	MinCodeLength = 4
+	MaxCodeLength = 32
+	// maxCounterCodeLength keeps the counter domain within 63 bits.
+	maxCounterCodeLength = 10
+	// counterBlockSize is how many counter values are reserved at a time.
+	counterBlockSize = 100
+)
+
+// ErrCodeSpaceExhausted is returned once a counter generator has issued every code.
+var ErrCodeSpaceExhausted = errors.New("short code space exhausted")
+
+// CodeGenerator produces short codes. attempt is 0 for the first try and is
+// increased after each collision, so deterministic generators can move on to
+// another code.
+type CodeGenerator interface {
+	Generate(originalURL string, attempt int) (string, error)
+}
+
+// CounterSource reserves blocks of counter values. Reserve returns the first
+// of n values that have never been handed out before, including by earlier
+// processes, so counter codes are not reissued after a restart.
+type CounterSource interface {
+	Reserve(n uint64) (uint64, error)
+}
+
+// NewCodeGenerator returns the generator for strategy. key and counter are
+// only used by the counter strategy.
+func NewCodeGenerator(strategy string, length int, key []byte, counter CounterSource) (CodeGenerator, error) {
+	switch strategy {
+	case CodeStrategyRandom:
+		return NewRandomCodeGenerator(length)
+	case CodeStrategyCounter:
+		return NewCounterCodeGenerator(length, key, counter)
+	case CodeStrategyHash:
+		return NewHashCodeGenerator(length)
+	default:
+		return nil, fmt.Errorf("unknown short code strategy %q", strategy)
+	}
+}
+
+// CheckCodeLength rejects a code length the strategy cannot generate: the
+// counter strategy allows shorter codes than the others.
+func CheckCodeLength(strategy string, length int) error {
+	if strategy == CodeStrategyCounter {
+		return checkCodeLength(length, maxCounterCodeLength)
+	}
+	return checkCodeLength(length, MaxCodeLength)
+}
+
+func checkCodeLength(length, maxLength int) error {
+	if length < MinCodeLength || length > maxLength {
+		return fmt.Errorf("short code length must be %d to %d, got %d", MinCodeLength, maxLength, length)
+	}
+	return nil
+}
+
+// RandomCodeGenerator returns uniformly random base62 codes.
+type RandomCodeGenerator struct {
+	length int
+}
+
+func NewRandomCodeGenerator(length int) (*RandomCodeGenerator, error) {
+	if err := checkCodeLength(length, MaxCodeLength); err != nil {
+		return nil, err
+	}
+	return &RandomCodeGenerator{length: length}, nil
+}
+
+func (g *RandomCodeGenerator) Generate(_ string, _ int) (string, error) {
+	code := make([]byte, g.length)
+	limit := big.NewInt(int64(len(base62Alphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		code[i] = base62Alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CounterCodeGenerator numbers codes sequentially and passes the counter
+// through a keyed bijection, so codes are unique but consecutive codes do not
+// look related. Counter values come from a CounterSource in blocks; values
+// left in a block when the process stops are never used.
+type CounterCodeGenerator struct {
+	mu     sync.Mutex
+	source CounterSource
+	next   uint64
+	end    uint64
+	length int
+	bits   uint
+	mask   uint64
+	mul    uint64
+	xor    uint64
+}
+
+func NewCounterCodeGenerator(length int, key []byte, source CounterSource) (*CounterCodeGenerator, error) {
+	if err := checkCodeLength(length, maxCounterCodeLength); err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, errors.New("counter codes need a counter source")
+	}
+
+	// The domain is the largest power of two that fits in length base62 digits.
+	bits := uint(float64(length) * math.Log2(float64(len(base62Alphabet))))
+	mask := uint64(1)<<bits - 1
+
+	sum := sha256.Sum256(key)
+	return &CounterCodeGenerator{
+		source: source,
+		length: length,
+		bits:   bits,
+		mask:   mask,
+		mul:    binary.BigEndian.Uint64(sum[0:8]) | 1,
+		xor:    binary.BigEndian.Uint64(sum[8:16]),
+	}, nil
+}
+
+func (g *CounterCodeGenerator) Generate(_ string, _ int) (string, error) {
+	g.mu.Lock()
+	if g.next == g.end {
+		first, err := g.source.Reserve(counterBlockSize)
+		if err != nil {
+			g.mu.Unlock()
+			return "", fmt.Errorf("reserve counter values: %w", err)
+		}
+		g.next, g.end = first, first+counterBlockSize
+	}
+	n := g.next
+	if n > g.mask {
+		g.mu.Unlock()
+		return "", ErrCodeSpaceExhausted
+	}
+	g.next++
+	g.mu.Unlock()
+
+	return encodeBase62(g.permute(n), g.length), nil
+}
+
+// permute is a bijection on [0, mask]: multiplication by an odd number,
+// xorshift and xor with a constant are each invertible modulo 2^bits.
+func (g *CounterCodeGenerator) permute(n uint64) uint64 {
+	n = (n * g.mul) & g.mask
+	n ^= n >> (g.bits / 2)
+	n = (n * g.mul) & g.mask
+	return n ^ (g.xor & g.mask)
+}
+
+// HashCodeGenerator derives the code from the URL, so the same URL always
+// gets the same code on the first attempt.
+type HashCodeGenerator struct {
+	length int
+}
+
+func NewHashCodeGenerator(length int) (*HashCodeGenerator, error) {
+	if err := checkCodeLength(length, MaxCodeLength); err != nil {
+		return nil, err
+	}
+	return &HashCodeGenerator{length: length}, nil
+}
+
+func (g *HashCodeGenerator) Generate(originalURL string, attempt int) (string, error) {
+	input := originalURL
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+
+	// 32 bytes hold more entropy than 32 base62 digits need.
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(base62Alphabet)))
+	digit := new(big.Int)
+	code := make([]byte, g.length)
+	for i := range code {
+		n.DivMod(n, base, digit)
+		code[i] = base62Alphabet[digit.Int64()]
+	}
+	return string(code), nil
+}
+
+// encodeBase62 writes n as exactly length digits, most significant first.
+func encodeBase62(n uint64, length int) string {
+	code := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		code[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(code)
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/lib"
+)
+
+func TestEnsureSelfSignedCert(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+
+	certFile, keyFile, err := lib.EnsureSelfSignedCert(dir, []string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load generated key pair: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse generated certificate: %v", err)
+	}
+	if err := cert.VerifyHostname("localhost"); err != nil {
+		t.Errorf("Expected certificate to be valid for localhost: %v", err)
+	}
+	if err := cert.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("Expected certificate to be valid for 127.0.0.1: %v", err)
+	}
+
+	before, _ := os.ReadFile(certFile)
+	if _, _, err := lib.EnsureSelfSignedCert(dir, []string{"localhost"}); err != nil {
+		t.Fatalf("Expected no error on second call, got %v", err)
+	}
+	after, _ := os.ReadFile(certFile)
+	if string(before) != string(after) {
+		t.Errorf("Expected cached certificate to be reused")
+	}
+}
+
+func TestEnsureSelfSignedCert_RegeneratesForNewHosts(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	if _, _, err := lib.EnsureSelfSignedCert(dir, []string{"localhost"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	certFile, keyFile, err := lib.EnsureSelfSignedCert(dir, []string{"localhost", "shortener.internal"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pair, _ := tls.LoadX509KeyPair(certFile, keyFile)
+	cert, _ := x509.ParseCertificate(pair.Certificate[0])
+	if err := cert.VerifyHostname("shortener.internal"); err != nil {
+		t.Errorf("Expected the certificate to be regenerated for the new host: %v", err)
+	}
+}
+
+func TestEnsureSelfSignedCert_RejectsSharedDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not checked on Windows")
+	}
+	dir := filepath.Join(t.TempDir(), "tls")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+	if _, _, err := lib.EnsureSelfSignedCert(dir, []string{"localhost"}); err == nil {
+		t.Error("Expected a directory writable by other users to be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/lib"
+)
+
+// counter is an in-memory lib.CounterSource.
+type counter struct {
+	next     uint64
+	reserved int
+}
+
+func (c *counter) Reserve(n uint64) (uint64, error) {
+	first := c.next
+	c.next += n
+	c.reserved++
+	return first, nil
+}
+
+func TestCodeGenerators(t *testing.T) {
+	key := []byte("secret")
+	for _, strategy := range []string{lib.CodeStrategyRandom, lib.CodeStrategyCounter, lib.CodeStrategyHash} {
+		t.Run(strategy, func(t *testing.T) {
+			codes, err := lib.NewCodeGenerator(strategy, 6, key, &counter{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			seen := make(map[string]bool)
+			for i := 0; i < 10000; i++ {
+				code, err := codes.Generate("https://example.com/"+string(rune('a'+i%26)), i/26)
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if len(code) != 6 {
+					t.Fatalf("Expected a 6 character code, got %q", code)
+				}
+				for _, r := range code {
+					if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+						t.Fatalf("Expected base62 code, got %q", code)
+					}
+				}
+				if strategy == lib.CodeStrategyCounter && seen[code] {
+					t.Fatalf("Counter generator repeated %q after %d codes", code, i)
+				}
+				seen[code] = true
+			}
+		})
+	}
+}
+
+func TestHashCodeGenerator_Deterministic(t *testing.T) {
+	codes, _ := lib.NewHashCodeGenerator(8)
+
+	first, _ := codes.Generate("https://example.com", 0)
+	second, _ := codes.Generate("https://example.com", 0)
+	if first != second {
+		t.Errorf("Expected the same code for the same URL, got %s and %s", first, second)
+	}
+
+	retry, _ := codes.Generate("https://example.com", 1)
+	if retry == first {
+		t.Errorf("Expected a different code on retry, got %s twice", first)
+	}
+}
+
+func TestCounterCodeGenerator_Exhausted(t *testing.T) {
+	codes, err := lib.NewCounterCodeGenerator(4, nil, &counter{next: 1<<23 - 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := codes.Generate("", 0); err != nil {
+		t.Fatalf("Expected the last code to be issued, got %v", err)
+	}
+	if _, err := codes.Generate("", 0); !errors.Is(err, lib.ErrCodeSpaceExhausted) {
+		t.Errorf("Expected ErrCodeSpaceExhausted, got %v", err)
+	}
+}
+
+func TestNewCodeGenerator_InvalidLength(t *testing.T) {
+	if _, err := lib.NewCodeGenerator(lib.CodeStrategyCounter, 11, nil, &counter{}); err == nil {
+		t.Error("Expected counter codes longer than 10 characters to be rejected")
+	}
+	if _, err := lib.NewCodeGenerator(lib.CodeStrategyRandom, 3, nil, nil); err == nil {
+		t.Error("Expected codes shorter than 4 characters to be rejected")
+	}
+}
+
+func TestCounterCodeGenerator_ResumesAfterReservedBlock(t *testing.T) {
+	source := &counter{}
+	first, _ := lib.NewCounterCodeGenerator(6, nil, source)
+	issued := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		code, err := first.Generate("", 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		issued[code] = true
+	}
+
+	// A restarted process reserves a fresh block instead of reusing the
+	// values the previous one left unused.
+	second, _ := lib.NewCounterCodeGenerator(6, nil, source)
+	for i := 0; i < 200; i++ {
+		code, err := second.Generate("", 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if issued[code] {
+			t.Fatalf("Expected %q not to be reissued after a restart", code)
+		}
+	}
+	if source.reserved != 3 {
+		t.Errorf("Expected 3 reserved blocks, got %d", source.reserved)
+	}
+}
+
+func TestNewCounterCodeGenerator_NeedsSource(t *testing.T) {
+	if _, err := lib.NewCounterCodeGenerator(6, nil, nil); err == nil {
+		t.Error("Expected an error without a counter source")
+	}
+}
@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SignUserID returns a "<userID>.<hmac>" token that VerifyUserID accepts.
+func SignUserID(userID, secret string) string {
+	return userID + "." + hex.EncodeToString(userIDSignature(userID, secret))
+}
+
+// VerifyUserID extracts the user ID from a token produced by SignUserID,
+// reporting false if the token was not signed with the same secret.
+func VerifyUserID(token, secret string) (string, bool) {
+	userID, signature, found := strings.Cut(token, ".")
+	if !found || userID == "" {
+		return "", false
+	}
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", false
+	}
+
+	if !hmac.Equal(decoded, userIDSignature(userID, secret)) {
+		return "", false
+	}
+	return userID, true
+}
+
+func userIDSignature(userID, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(userID))
+	return mac.Sum(nil)
+}
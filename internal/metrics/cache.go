@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterCache exposes the hit, miss and size counters of a URL cache.
+func (m *Metrics) RegisterCache(cache *repository.CachedStorage) error {
+	return m.Register(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Short URL resolutions served from the cache.",
+		}, func() float64 { return float64(cache.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Short URL resolutions that went to storage.",
+		}, func() float64 { return float64(cache.Stats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Short URLs currently cached.",
+		}, func() float64 { return float64(cache.Stats().Size) }),
+	)
+}
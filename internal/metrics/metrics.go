@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+// Metrics owns the Prometheus registry of the service and its collectors.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	shortens        *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Short URL visits by response status.",
+		}, []string{"status"}),
+		shortens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_total",
+			Help:      "Shorten requests by outcome: created, conflict, rejected or failed.",
+		}, []string{"outcome"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage call latency by backend, method and result.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"backend", "method", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.shortens,
+		m.storageDuration,
+	)
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Register adds extra collectors, such as cache counters, to the registry.
+func (m *Metrics) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ObserveRequest records a finished HTTP request. route is the matched route
+// pattern, not the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveRedirect counts a visit to a short URL.
+func (m *Metrics) ObserveRedirect(status int) {
+	m.redirects.WithLabelValues(strconv.Itoa(status)).Inc()
+}
+
+// ObserveShorten counts a shorten request by the status it was answered with.
+func (m *Metrics) ObserveShorten(status int) {
+	m.shortens.WithLabelValues(shortenOutcome(status)).Inc()
+}
+
+func shortenOutcome(status int) string {
+	switch {
+	case status == http.StatusCreated:
+		return "created"
+	case status == http.StatusConflict:
+		return "conflict"
+	case status < http.StatusInternalServerError:
+		return "rejected"
+	default:
+		return "failed"
+	}
+}
+
+// ObserveStorage records the latency of a storage call.
+func (m *Metrics) ObserveStorage(backend, method, result string, duration time.Duration) {
+	m.storageDuration.WithLabelValues(backend, method, result).Observe(duration.Seconds())
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+// Storage is a repository.Storage decorator that records the latency of
+// every call. Expected outcomes such as a missing or duplicate URL are
+// labelled "rejected" so the "error" series only counts real failures.
+type Storage struct {
+	storage repository.Storage
+	backend string
+	metrics *Metrics
+}
+
+var _ repository.Storage = (*Storage)(nil)
+
+func InstrumentStorage(storage repository.Storage, backend string, m *Metrics) *Storage {
+	return &Storage{storage: storage, backend: backend, metrics: m}
+}
+
+func (s *Storage) observe(method string, start time.Time, err error) {
+	s.metrics.ObserveStorage(s.backend, method, storageResult(err), time.Since(start))
+}
+
+func storageResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, repository.ErrNotFound),
+		errors.Is(err, repository.ErrDuplicateURL),
+		errors.Is(err, repository.ErrShortURLTaken),
+		errors.Is(err, repository.ErrDeleted),
+		errors.Is(err, repository.ErrExpired):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
+
+func (s *Storage) CreateShortURL(
+	ctx context.Context,
+	uuid, shortURL, originalURL, userID string,
+	limits repository.URLLimits,
+) (string, error) {
+	start := time.Now()
+	stored, err := s.storage.CreateShortURL(ctx, uuid, shortURL, originalURL, userID, limits)
+	s.observe("CreateShortURL", start, err)
+	return stored, err
+}
+
+func (s *Storage) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
+	start := time.Now()
+	originalURL, err := s.storage.GetOriginalURL(ctx, shortURL)
+	s.observe("GetOriginalURL", start, err)
+	return originalURL, err
+}
+
+func (s *Storage) LookupURL(ctx context.Context, shortURL string) (repository.URLRecord, error) {
+	start := time.Now()
+	record, err := s.storage.LookupURL(ctx, shortURL)
+	s.observe("LookupURL", start, err)
+	return record, err
+}
+
+func (s *Storage) CreateBatchURLs(
+	ctx context.Context,
+	urls []repository.BatchURLRequest,
+) ([]repository.BatchURLOutput, error) {
+	start := time.Now()
+	output, err := s.storage.CreateBatchURLs(ctx, urls)
+	s.observe("CreateBatchURLs", start, err)
+	return output, err
+}
+
+func (s *Storage) GetUserURLs(ctx context.Context, userID string) ([]repository.URLRecord, error) {
+	start := time.Now()
+	records, err := s.storage.GetUserURLs(ctx, userID)
+	s.observe("GetUserURLs", start, err)
+	return records, err
+}
+
+func (s *Storage) ListURLs(ctx context.Context, after string, limit int) ([]repository.URLRecord, error) {
+	start := time.Now()
+	records, err := s.storage.ListURLs(ctx, after, limit)
+	s.observe("ListURLs", start, err)
+	return records, err
+}
+
+func (s *Storage) DeleteURLs(ctx context.Context, owned map[string][]string) error {
+	start := time.Now()
+	err := s.storage.DeleteURLs(ctx, owned)
+	s.observe("DeleteURLs", start, err)
+	return err
+}
+
+func (s *Storage) RecordClicks(ctx context.Context, events []repository.ClickEvent) error {
+	start := time.Now()
+	err := s.storage.RecordClicks(ctx, events)
+	s.observe("RecordClicks", start, err)
+	return err
+}
+
+func (s *Storage) ListClicks(ctx context.Context, from, to string) ([]repository.ClickEvent, error) {
+	start := time.Now()
+	events, err := s.storage.ListClicks(ctx, from, to)
+	s.observe("ListClicks", start, err)
+	return events, err
+}
+
+func (s *Storage) GetURLStats(ctx context.Context, shortURL string) (repository.URLStats, error) {
+	start := time.Now()
+	stats, err := s.storage.GetURLStats(ctx, shortURL)
+	s.observe("GetURLStats", start, err)
+	return stats, err
+}
+
+func (s *Storage) PurgeExpired(ctx context.Context) (int, error) {
+	start := time.Now()
+	purged, err := s.storage.PurgeExpired(ctx)
+	s.observe("PurgeExpired", start, err)
+	return purged, err
+}
+
+func (s *Storage) CountURLs(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := s.storage.CountURLs(ctx)
+	s.observe("CountURLs", start, err)
+	return count, err
+}
+
+func (s *Storage) ReserveCounter(ctx context.Context, n uint64) (uint64, error) {
+	start := time.Now()
+	first, err := s.storage.ReserveCounter(ctx, n)
+	s.observe("ReserveCounter", start, err)
+	return first, err
+}
+
+func (s *Storage) AdvanceCounter(ctx context.Context, atLeast uint64) (uint64, error) {
+	start := time.Now()
+	value, err := s.storage.AdvanceCounter(ctx, atLeast)
+	s.observe("AdvanceCounter", start, err)
+	return value, err
+}
+
+func (s *Storage) CountUsers(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := s.storage.CountUsers(ctx)
+	s.observe("CountUsers", start, err)
+	return count, err
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.storage.Ping(ctx)
+	s.observe("Ping", start, err)
+	return err
+}
+
+func (s *Storage) Close() error {
+	return s.storage.Close()
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/metrics"
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	return string(body)
+}
+
+func TestInstrumentStorage(t *testing.T) {
+	m := metrics.New()
+	storage := metrics.InstrumentStorage(repository.NewInMemoryStorage(), "memory", m)
+	ctx := context.Background()
+
+	if _, err := storage.CreateShortURL(ctx, "uuid", "short1", "https://example.com/", "", repository.URLLimits{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := storage.GetOriginalURL(ctx, "missing"); err == nil {
+		t.Fatal("Expected an error for a missing URL")
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`shortener_storage_operation_duration_seconds_count{backend="memory",method="CreateShortURL",result="ok"} 1`,
+		`shortener_storage_operation_duration_seconds_count{backend="memory",method="GetOriginalURL",result="rejected"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestRegisterCache(t *testing.T) {
+	m := metrics.New()
+	cache := repository.NewCachedStorage(repository.NewInMemoryStorage(), repository.CacheOptions{Size: 10})
+	if err := m.RegisterCache(cache); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, _ = cache.GetOriginalURL(context.Background(), "missing")
+
+	body := scrape(t, m)
+	if !strings.Contains(body, "shortener_cache_misses_total 1") {
+		t.Errorf("Expected one cache miss in metrics, got:\n%s", body)
+	}
+}
@@ -1,14 +1,24 @@
 package models
 
+import "time"
+
 // easyjson:json
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL       string     `json:"url" binding:"required"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       int64      `json:"ttl,omitempty"` // seconds
+	MaxClicks int        `json:"max_clicks,omitempty"`
 }
 
 // easyjson:json
 type BatchShortenRequest struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"` // seconds
+	MaxClicks     int        `json:"max_clicks,omitempty"`
 }
 
 // easyjson:json
@@ -21,3 +31,28 @@ type BatchShortenResponse struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
 }
+
+// easyjson:json
+type UserURLResponse struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
+
+// easyjson:json
+type URLStatsResponse struct {
+	TotalClicks    int           `json:"total_clicks"`
+	UniqueVisitors int           `json:"unique_visitors"`
+	Daily          []DailyClicks `json:"daily"`
+}
+
+// easyjson:json
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+// easyjson:json
+type InternalStatsResponse struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
@@ -7,6 +7,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -17,7 +18,192 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener(in *jlexer.Lexer, out *ShortenResponse) {
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels(in *jlexer.Lexer, out *UserURLResponse) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "short_url":
+			out.ShortURL = string(in.String())
+		case "original_url":
+			out.OriginalURL = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels(out *jwriter.Writer, in UserURLResponse) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"original_url\":"
+		out.RawString(prefix)
+		out.String(string(in.OriginalURL))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UserURLResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UserURLResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UserURLResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UserURLResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels1(in *jlexer.Lexer, out *URLStatsResponse) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "total_clicks":
+			out.TotalClicks = int(in.Int())
+		case "unique_visitors":
+			out.UniqueVisitors = int(in.Int())
+		case "daily":
+			if in.IsNull() {
+				in.Skip()
+				out.Daily = nil
+			} else {
+				in.Delim('[')
+				if out.Daily == nil {
+					if !in.IsDelim(']') {
+						out.Daily = make([]DailyClicks, 0, 2)
+					} else {
+						out.Daily = []DailyClicks{}
+					}
+				} else {
+					out.Daily = (out.Daily)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 DailyClicks
+					(v1).UnmarshalEasyJSON(in)
+					out.Daily = append(out.Daily, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels1(out *jwriter.Writer, in URLStatsResponse) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"total_clicks\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TotalClicks))
+	}
+	{
+		const prefix string = ",\"unique_visitors\":"
+		out.RawString(prefix)
+		out.Int(int(in.UniqueVisitors))
+	}
+	{
+		const prefix string = ",\"daily\":"
+		out.RawString(prefix)
+		if in.Daily == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Daily {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v URLStatsResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v URLStatsResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *URLStatsResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *URLStatsResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels1(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels2(in *jlexer.Lexer, out *ShortenResponse) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -48,7 +234,7 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener(in *jlexer
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener(out *jwriter.Writer, in ShortenResponse) {
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels2(out *jwriter.Writer, in ShortenResponse) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -63,27 +249,27 @@ func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener(out *jwrit
 // MarshalJSON supports json.Marshaler interface
 func (v ShortenResponse) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener(&w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels2(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ShortenResponse) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener(w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels2(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ShortenResponse) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener(&r, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels2(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ShortenResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener(l, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels2(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener1(in *jlexer.Lexer, out *ShortenRequest) {
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels3(in *jlexer.Lexer, out *ShortenRequest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -104,6 +290,24 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener1(in *jlexe
 		switch key {
 		case "url":
 			out.URL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "ttl":
+			out.TTL = int64(in.Int64())
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -114,7 +318,7 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener1(in *jlexe
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener1(out *jwriter.Writer, in ShortenRequest) {
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels3(out *jwriter.Writer, in ShortenRequest) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -123,33 +327,199 @@ func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener1(out *jwri
 		out.RawString(prefix[1:])
 		out.String(string(in.URL))
 	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.TTL != 0 {
+		const prefix string = ",\"ttl\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TTL))
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v ShortenRequest) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener1(&w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels3(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ShortenRequest) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener1(w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels3(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ShortenRequest) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener1(&r, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels3(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ShortenRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener1(l, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels3(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels4(in *jlexer.Lexer, out *InternalStatsResponse) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "urls":
+			out.URLs = int(in.Int())
+		case "users":
+			out.Users = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels4(out *jwriter.Writer, in InternalStatsResponse) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"urls\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.URLs))
+	}
+	{
+		const prefix string = ",\"users\":"
+		out.RawString(prefix)
+		out.Int(int(in.Users))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v InternalStatsResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v InternalStatsResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *InternalStatsResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *InternalStatsResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels4(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels5(in *jlexer.Lexer, out *DailyClicks) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "date":
+			out.Date = string(in.String())
+		case "clicks":
+			out.Clicks = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels5(out *jwriter.Writer, in DailyClicks) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"date\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Date))
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v DailyClicks) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
 }
-func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener2(in *jlexer.Lexer, out *BatchShortenResponse) {
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v DailyClicks) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *DailyClicks) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *DailyClicks) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels5(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels6(in *jlexer.Lexer, out *BatchShortenResponse) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -182,7 +552,7 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener2(in *jlexe
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener2(out *jwriter.Writer, in BatchShortenResponse) {
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels6(out *jwriter.Writer, in BatchShortenResponse) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -202,27 +572,27 @@ func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener2(out *jwri
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenResponse) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener2(&w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels6(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenResponse) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener2(w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels6(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenResponse) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener2(&r, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels6(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener2(l, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels6(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener3(in *jlexer.Lexer, out *BatchShortenRequest) {
+func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels7(in *jlexer.Lexer, out *BatchShortenRequest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -245,6 +615,24 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener3(in *jlexe
 			out.CorrelationID = string(in.String())
 		case "original_url":
 			out.OriginalURL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "ttl":
+			out.TTL = int64(in.Int64())
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -255,7 +643,7 @@ func easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener3(in *jlexe
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener3(out *jwriter.Writer, in BatchShortenRequest) {
+func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels7(out *jwriter.Writer, in BatchShortenRequest) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -269,29 +657,49 @@ func easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener3(out *jwri
 		out.RawString(prefix)
 		out.String(string(in.OriginalURL))
 	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.TTL != 0 {
+		const prefix string = ",\"ttl\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TTL))
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenRequest) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener3(&w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels7(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenRequest) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerCmdShortener3(w, v)
+	easyjsonD2b7633eEncodeGithubComHairutdinUrlShortenerInternalModels7(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenRequest) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener3(&r, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels7(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerCmdShortener3(l, v)
+	easyjsonD2b7633eDecodeGithubComHairutdinUrlShortenerInternalModels7(l, v)
 }
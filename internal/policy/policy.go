@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrBlocked = errors.New("host is not allowed")
+
+// BlockedError names the rule that rejected a host.
+type BlockedError struct {
+	Host string
+	Rule string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("host %q is not allowed by rule %q", e.Host, e.Rule)
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// notAllowlisted is reported when allow rules exist and none matched.
+const notAllowlisted = "not in allowlist"
+
+type rule struct {
+	text   string
+	exact  string
+	suffix string
+	regex  *regexp.Regexp
+}
+
+func (r rule) matches(host string) bool {
+	switch {
+	case r.regex != nil:
+		return r.regex.MatchString(host)
+	case r.suffix != "":
+		return host == r.suffix || strings.HasSuffix(host, "."+r.suffix)
+	default:
+		return host == r.exact
+	}
+}
+
+type ruleSet struct {
+	allow []rule
+	deny  []rule
+}
+
+// parse reads rules, one per line:
+//
+//	deny  evil.example        exact host
+//	deny  *.evil.example      the domain and all its subdomains
+//	allow ~^[a-z]+\.corp\.example$   regular expression
+//
+// Blank lines and lines starting with # are ignored. Deny rules win; when any
+// allow rule exists, hosts matching none of them are denied.
+func parse(data []byte) (*ruleSet, error) {
+	set := &ruleSet{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"allow|deny <pattern>\"", lineNo)
+		}
+		action, pattern := fields[0], fields[1]
+
+		r := rule{text: action + " " + pattern}
+		switch {
+		case strings.HasPrefix(pattern, "~"):
+			re, err := regexp.Compile(pattern[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			r.regex = re
+		case strings.HasPrefix(pattern, "*."):
+			r.suffix = strings.ToLower(pattern[2:])
+		default:
+			r.exact = strings.ToLower(pattern)
+		}
+
+		switch action {
+		case "allow":
+			set.allow = append(set.allow, r)
+		case "deny":
+			set.deny = append(set.deny, r)
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q", lineNo, action)
+		}
+	}
+	return set, scanner.Err()
+}
+
+func (s *ruleSet) check(host string) error {
+	for _, r := range s.deny {
+		if r.matches(host) {
+			return &BlockedError{Host: host, Rule: r.text}
+		}
+	}
+	if len(s.allow) == 0 {
+		return nil
+	}
+	for _, r := range s.allow {
+		if r.matches(host) {
+			return nil
+		}
+	}
+	return &BlockedError{Host: host, Rule: notAllowlisted}
+}
+
+// Policy holds the allow and deny rules loaded from a file. It re-reads the
+// file when its modification time changes and on Reload; a file that fails
+// to parse is logged and the previous rules stay in effect.
+type Policy struct {
+	path     string
+	logger   *zap.Logger
+	rules    atomic.Pointer[ruleSet]
+	mu       sync.Mutex // serializes reloads
+	modTime  time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New loads the rules from path and checks the file for changes every
+// interval; a zero interval disables watching.
+func New(path string, logger *zap.Logger, interval time.Duration) (*Policy, error) {
+	p := &Policy{
+		path:   path,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		p.wg.Add(1)
+		go p.watch(interval)
+	}
+	return p, nil
+}
+
+// Check returns a *BlockedError if the rules do not allow host.
+func (p *Policy) Check(host string) error {
+	return p.rules.Load().check(strings.ToLower(host))
+}
+
+// Reload re-reads the rules file.
+func (p *Policy) Reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+	// Remember the version even if it is invalid, so the watcher reports it once.
+	p.modTime = info.ModTime()
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+	set, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("policy file %s: %w", p.path, err)
+	}
+
+	p.rules.Store(set)
+	p.logger.Info("loaded URL policy",
+		zap.String("path", p.path),
+		zap.Int("allow", len(set.allow)),
+		zap.Int("deny", len(set.deny)),
+	)
+	return nil
+}
+
+// Close stops watching the rules file.
+func (p *Policy) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+}
+
+func (p *Policy) watch(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !p.changed() {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				p.logger.Error("failed to reload URL policy", zap.Error(err))
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Policy) changed() bool {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !info.ModTime().Equal(p.modTime)
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"go.uber.org/zap"
+)
+
+func writeRules(t *testing.T, path, rules string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writeRules(t, path, `
+# phishing
+deny  phish.example
+deny  *.bad.example
+deny  ~^login-[a-z]+\.example$
+`)
+
+	p, err := policy.New(path, zap.NewNop(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		host     string
+		wantRule string
+	}{
+		{"example.com", ""},
+		{"phish.example", "deny phish.example"},
+		{"PHISH.example", "deny phish.example"},
+		{"sub.phish.example", ""},
+		{"bad.example", "deny *.bad.example"},
+		{"a.b.bad.example", "deny *.bad.example"},
+		{"notbad.example", ""},
+		{"login-bank.example", `deny ~^login-[a-z]+\.example$`},
+	}
+	for _, tt := range tests {
+		err := p.Check(tt.host)
+		if tt.wantRule == "" {
+			if err != nil {
+				t.Errorf("%s: expected allowed, got %v", tt.host, err)
+			}
+			continue
+		}
+		var blocked *policy.BlockedError
+		if !errors.As(err, &blocked) || blocked.Rule != tt.wantRule {
+			t.Errorf("%s: expected rule %q, got %v", tt.host, tt.wantRule, err)
+		}
+	}
+}
+
+func TestPolicy_Allowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writeRules(t, path, "allow *.corp.example\ndeny legacy.corp.example\n")
+
+	p, err := policy.New(path, zap.NewNop(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer p.Close()
+
+	if err := p.Check("wiki.corp.example"); err != nil {
+		t.Errorf("Expected allowlisted host to pass, got %v", err)
+	}
+	if err := p.Check("legacy.corp.example"); !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected deny to win over allow, got %v", err)
+	}
+	if err := p.Check("example.com"); !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected host outside the allowlist to be blocked, got %v", err)
+	}
+}
+
+func TestPolicy_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writeRules(t, path, "block evil.example\n")
+
+	if _, err := policy.New(path, zap.NewNop(), 0); err == nil {
+		t.Error("Expected an unknown action to be rejected")
+	}
+}
+
+func TestPolicy_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writeRules(t, path, "deny old.example\n")
+
+	p, err := policy.New(path, zap.NewNop(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer p.Close()
+
+	writeRules(t, path, "deny new.example\n")
+	// Make sure the modification time differs on coarse-grained filesystems.
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to touch rules: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Check("new.example") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the changed file to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.Check("old.example"); err != nil {
+		t.Errorf("Expected old rule to be gone, got %v", err)
+	}
+
+	// A broken file keeps the previous rules in effect.
+	writeRules(t, path, "deny ~[\n")
+	if err := p.Reload(); err == nil {
+		t.Error("Expected reload of an invalid file to fail")
+	}
+	if err := p.Check("new.example"); !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected previous rules to stay in effect, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/ratelimit"
+)
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(0)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limit := ratelimit.PerMinute(1, 3)
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "client", limit)
+		if err != nil || !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed, got %+v, %v", i+1, result, err)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("Expected %d remaining, got %d", 2-i, result.Remaining)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "client", limit)
+	if result.Allowed {
+		t.Fatal("Expected the fourth request to be rejected")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+		t.Errorf("Expected retry within a minute, got %s", result.RetryAfter)
+	}
+	if result.Limit != 3 || result.Remaining != 0 {
+		t.Errorf("Expected limit 3 and nothing remaining, got %+v", result)
+	}
+
+	if result, _ := limiter.Allow(ctx, "other", limit); !result.Allowed {
+		t.Error("Expected another client to have its own bucket")
+	}
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(0)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limit := ratelimit.Limit{Rate: 50, Burst: 1}
+
+	if result, _ := limiter.Allow(ctx, "client", limit); !result.Allowed {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	if result, _ := limiter.Allow(ctx, "client", limit); result.Allowed {
+		t.Fatal("Expected the bucket to be empty")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "client", limit); !result.Allowed {
+		t.Error("Expected a token after the refill interval")
+	}
+}
+
+func TestMemoryLimiter_EvictsRefilledBuckets(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(10 * time.Millisecond)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	_, _ = limiter.Allow(ctx, "fast", ratelimit.Limit{Rate: 1000, Burst: 1})
+	_, _ = limiter.Allow(ctx, "slow", ratelimit.PerMinute(1, 1))
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected only the still empty bucket to remain, got %d buckets", limiter.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+// checkBatch rejects a batch that reuses a short URL, or the original URL of
+// a live record, whether already stored or earlier in the same batch,
+// mirroring the unique indexes of the Postgres schema so a batch is stored
+// entirely or not at all.
+func checkBatch(urls []BatchURLRequest, stored map[string]URLRecord, byOriginal map[string]string) error {
+	shorts := make(map[string]struct{}, len(urls))
+	originals := make(map[string]string, len(urls))
+	for _, url := range urls {
+		if _, exists := stored[url.ShortURL]; exists {
+			return ErrShortURLTaken
+		}
+		if _, exists := shorts[url.ShortURL]; exists {
+			return ErrShortURLTaken
+		}
+		shorts[url.ShortURL] = struct{}{}
+		if url.Deleted {
+			continue
+		}
+		if existing, exists := byOriginal[url.OriginalURL]; exists {
+			return &DuplicateURLError{ShortURL: existing}
+		}
+		if existing, exists := originals[url.OriginalURL]; exists {
+			return &DuplicateURLError{ShortURL: existing}
+		}
+		originals[url.OriginalURL] = url.ShortURL
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOptions configure CachedStorage.
+type CacheOptions struct {
+	// Size is the maximum number of cached short URLs.
+	Size int
+	// TTL bounds how long a resolved URL is served from the cache.
+	TTL time.Duration
+	// NegativeTTL bounds how long unknown and deleted short URLs are remembered.
+	NegativeTTL time.Duration
+}
+
+// CacheStats are the counters of a CachedStorage.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+type cacheEntry struct {
+	shortURL    string
+	originalURL string
+	err         error
+	expiresAt   time.Time
+}
+
+// CachedStorage is a Storage decorator that keeps recently resolved short
+// URLs in a size-bounded LRU. URLs with a click limit are never cached, since
+// every visit has to be counted by the underlying storage. Writes made
+// through this instance invalidate their entries; changes made by other
+// instances show up once the entries expire.
+type CachedStorage struct {
+	Storage
+
+	opts    CacheOptions
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+	// gen changes on every invalidation, so a lookup that raced with a
+	// write does not cache what it read before the write.
+	gen    uint64
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+var _ Storage = (*CachedStorage)(nil)
+
+func NewCachedStorage(storage Storage, opts CacheOptions) *CachedStorage {
+	return &CachedStorage{
+		Storage: storage,
+		opts:    opts,
+		entries: make(map[string]*list.Element, opts.Size),
+		order:   list.New(),
+	}
+}
+
+func (c *CachedStorage) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
+	if entry, ok := c.get(shortURL); ok {
+		c.hits.Add(1)
+		return entry.originalURL, entry.err
+	}
+	c.misses.Add(1)
+
+	gen := c.generation()
+	record, err := c.Storage.LookupURL(ctx, shortURL)
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrDeleted):
+		c.put(gen, cacheEntry{shortURL: shortURL, err: err, expiresAt: time.Now().Add(c.opts.NegativeTTL)})
+		return "", err
+	case err != nil:
+		return "", err
+	case record.MaxClicks > 0:
+		return c.Storage.GetOriginalURL(ctx, shortURL)
+	}
+
+	expiresAt := time.Now().Add(c.opts.TTL)
+	if record.ExpiresAt != nil && record.ExpiresAt.Before(expiresAt) {
+		expiresAt = *record.ExpiresAt
+	}
+	c.put(gen, cacheEntry{shortURL: shortURL, originalURL: record.OriginalURL, expiresAt: expiresAt})
+	return record.OriginalURL, nil
+}
+
+func (c *CachedStorage) CreateShortURL(
+	ctx context.Context,
+	uuid, shortURL, originalURL, userID string,
+	limits URLLimits,
+) (string, error) {
+	c.invalidate(shortURL)
+	return c.Storage.CreateShortURL(ctx, uuid, shortURL, originalURL, userID, limits)
+}
+
+func (c *CachedStorage) CreateBatchURLs(ctx context.Context, urls []BatchURLRequest) ([]BatchURLOutput, error) {
+	shortURLs := make([]string, len(urls))
+	for i, u := range urls {
+		shortURLs[i] = u.ShortURL
+	}
+	c.invalidate(shortURLs...)
+	return c.Storage.CreateBatchURLs(ctx, urls)
+}
+
+func (c *CachedStorage) DeleteURLs(ctx context.Context, owned map[string][]string) error {
+	err := c.Storage.DeleteURLs(ctx, owned)
+	for _, shortURLs := range owned {
+		c.invalidate(shortURLs...)
+	}
+	return err
+}
+
+// PurgeExpired purges the underlying storage and drops expired cache entries.
+func (c *CachedStorage) PurgeExpired(ctx context.Context) (int, error) {
+	purged, err := c.Storage.PurgeExpired(ctx)
+
+	now := time.Now()
+	c.mu.Lock()
+	for e := c.order.Back(); e != nil; {
+		prev := e.Prev()
+		if entry := e.Value.(*cacheEntry); !now.Before(entry.expiresAt) {
+			c.removeElement(e)
+		}
+		e = prev
+	}
+	c.mu.Unlock()
+	return purged, err
+}
+
+// Stats returns the hit and miss counters and the number of cached entries.
+func (c *CachedStorage) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Size: size}
+}
+
+func (c *CachedStorage) get(shortURL string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[shortURL]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.removeElement(e)
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(e)
+	return *entry, true
+}
+
+func (c *CachedStorage) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *CachedStorage) put(gen uint64, entry cacheEntry) {
+	if c.opts.Size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if e, ok := c.entries[entry.shortURL]; ok {
+		e.Value = &entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[entry.shortURL] = c.order.PushFront(&entry)
+	for c.order.Len() > c.opts.Size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *CachedStorage) invalidate(shortURLs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, shortURL := range shortURLs {
+		if e, ok := c.entries[shortURL]; ok {
+			c.removeElement(e)
+		}
+	}
+}
+
+// removeElement drops an entry. The caller must hold the lock.
+func (c *CachedStorage) removeElement(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).shortURL)
+}
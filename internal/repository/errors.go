@@ -2,4 +2,24 @@ package repository
 
 import "errors"
 
-var ErrDuplicateURL = errors.New("URL already exists")
+var (
+	ErrNotFound      = errors.New("URL not found")
+	ErrDuplicateURL  = errors.New("URL already exists")
+	ErrDeleted       = errors.New("URL is deleted")
+	ErrShortURLTaken = errors.New("short URL is already taken")
+	ErrExpired       = errors.New("URL has expired")
+)
+
+// DuplicateURLError reports that the original URL is already shortened and
+// carries its existing short code. It matches ErrDuplicateURL with errors.Is.
+type DuplicateURLError struct {
+	ShortURL string
+}
+
+func (e *DuplicateURLError) Error() string {
+	return ErrDuplicateURL.Error() + ": " + e.ShortURL
+}
+
+func (e *DuplicateURLError) Is(target error) bool {
+	return target == ErrDuplicateURL
+}
@@ -1,122 +1,622 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
-	"os"
 	"sync"
+	"time"
 )
 
+const (
+	// clicksFileSuffix names the JSON-lines file, next to the URL log, that
+	// click events are appended to.
+	clicksFileSuffix = ".clicks"
+
+	syncInterval = time.Second
+)
+
+// FileStorageOptions tunes durability and compaction of FileStorage.
+type FileStorageOptions struct {
+	Sync SyncPolicy
+	// CompactInterval is how often stale log lines are compacted away; zero
+	// disables periodic compaction.
+	CompactInterval time.Duration
+}
+
+// logEntry is one line of the URL log. Each line carries the latest state of
+// a record; Removed marks a record purged from storage.
+type logEntry struct {
+	URLRecord
+	Removed bool `json:"removed,omitempty"`
+}
+
+// counterEntry is a line of the URL log holding the next short-code counter
+// value. The last one wins.
+type counterEntry struct {
+	Counter uint64 `json:"counter"`
+}
+
+// FileStorage keeps URLs in memory and persists every change as a line
+// appended to a JSON-lines log, which is replayed on startup.
 type FileStorage struct {
-	filePath string
-	mu       sync.RWMutex
-	urls     map[string]string // shortURL -> originalURL
+	filePath   string
+	mu         sync.RWMutex
+	urls       map[string]URLRecord    // shortURL -> record
+	byOriginal map[string]string       // originalURL -> shortURL
+	userURLs   map[string][]string     // userID -> shortURLs
+	clicks     map[string][]ClickEvent // shortURL -> click events
+	deleted    int                     // soft-deleted records, kept so counts need no scan
+	live       liveOwners              // owners of records not deleted, kept so counts need no scan
+	codes      codeIndex               // short URLs in byte order, for listing
+	counter    uint64                  // next short-code counter value
+	urlLog     *jsonlLog
+	clicksLog  *jsonlLog
+	stop       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
 }
 
-func NewFileStorage(filePath string) (*FileStorage, error) {
-	fs := &FileStorage{filePath: filePath, urls: make(map[string]string)}
-	if err := fs.loadFromFile(); err != nil {
+func NewFileStorage(filePath string, opts FileStorageOptions) (*FileStorage, error) {
+	if opts.Sync == "" {
+		opts.Sync = SyncInterval
+	}
+
+	fs := &FileStorage{
+		filePath:   filePath,
+		urls:       make(map[string]URLRecord),
+		byOriginal: make(map[string]string),
+		userURLs:   make(map[string][]string),
+		clicks:     make(map[string][]ClickEvent),
+		stop:       make(chan struct{}),
+	}
+
+	var err error
+	if fs.urlLog, err = openJSONLLog(filePath, opts.Sync); err != nil {
+		return nil, err
+	}
+	if fs.clicksLog, err = openJSONLLog(filePath+clicksFileSuffix, opts.Sync); err != nil {
+		fs.urlLog.close()
 		return nil, err
 	}
+	if err := fs.load(); err != nil {
+		fs.urlLog.close()
+		fs.clicksLog.close()
+		return nil, err
+	}
+
+	fs.wg.Add(1)
+	go fs.run(opts)
+
 	return fs, nil
 }
 
-func (f *FileStorage) loadFromFile() error {
-	if _, err := os.Stat(f.filePath); os.IsNotExist(err) {
+func (f *FileStorage) load() error {
+	legacy := false
+	first := true
+	err := f.urlLog.replay(func(line []byte) error {
+		if first {
+			first = false
+			if records, ok := parseLegacyFile(line); ok {
+				for _, record := range records {
+					f.put(record)
+				}
+				legacy = true
+				return nil
+			}
+		}
+
+		var entry struct {
+			logEntry
+			Counter *uint64 `json:"counter"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if entry.Counter != nil {
+			f.counter = *entry.Counter
+			return nil
+		}
+		if entry.ShortURL == "" {
+			return errors.New("record without short_url")
+		}
+		if old, exists := f.urls[entry.ShortURL]; exists {
+			f.remove(old)
+		}
+		if !entry.Removed {
+			f.put(entry.URLRecord)
+		}
 		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	data, err := os.ReadFile(f.filePath)
+	err = f.clicksLog.replay(func(line []byte) error {
+		var event ClickEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		if _, exists := f.urls[event.ShortURL]; !exists {
+			// Left over from a purged URL; compaction drops it.
+			return nil
+		}
+		f.clicks[event.ShortURL] = append(f.clicks[event.ShortURL], event)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(data, &f.urls)
+	if legacy {
+		log.Println("Converting URL file to the JSON-lines format")
+		return f.compactURLs()
+	}
+	return nil
 }
 
-func (f *FileStorage) saveToFile() error {
-	log.Println("Saving to file without acquiring lock")
-	data, err := json.Marshal(f.urls)
-	if err != nil {
-		return err
+// parseLegacyFile recognizes files written before the JSON-lines log, which
+// held a single JSON object of shortURL -> record, or of shortURL ->
+// originalURL before URLs had owners.
+func parseLegacyFile(line []byte) ([]URLRecord, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil, false
+	}
+	if _, isEntry := fields["short_url"]; isEntry {
+		return nil, false
 	}
 
-	err = os.WriteFile(f.filePath, data, 0644)
-	if err != nil {
-		log.Println("Error writing to file:", err)
+	var records []URLRecord
+	var byShort map[string]URLRecord
+	if err := json.Unmarshal(line, &byShort); err == nil {
+		for short, record := range byShort {
+			record.ShortURL = short
+			records = append(records, record)
+		}
+		return records, true
+	}
+
+	var legacy map[string]string
+	if err := json.Unmarshal(line, &legacy); err != nil {
+		return nil, false
+	}
+	for short, original := range legacy {
+		records = append(records, URLRecord{ShortURL: short, OriginalURL: original})
 	}
-	log.Println("File saved successfully in saveToFile")
-	return err
+	return records, true
 }
 
-func (f *FileStorage) CreateShortURL(_, shortURL, originalURL string) (string, error) {
+// run syncs the logs for SyncInterval and compacts them periodically.
+func (f *FileStorage) run(opts FileStorageOptions) {
+	defer f.wg.Done()
+
+	var syncC, compactC <-chan time.Time
+	if opts.Sync == SyncInterval {
+		ticker := time.NewTicker(syncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if opts.CompactInterval > 0 {
+		ticker := time.NewTicker(opts.CompactInterval)
+		defer ticker.Stop()
+		compactC = ticker.C
+	}
+
+	for {
+		select {
+		case <-syncC:
+			f.mu.Lock()
+			err := errors.Join(f.urlLog.sync(), f.clicksLog.sync())
+			f.mu.Unlock()
+			if err != nil {
+				log.Println("Error syncing file storage:", err)
+			}
+		case <-compactC:
+			if err := f.Compact(); err != nil {
+				log.Println("Error compacting file storage:", err)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Compact rewrites the logs so they hold only the current state, dropping
+// superseded and removed records.
+func (f *FileStorage) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.urlLog.lines > len(f.urls)+f.counterLines() {
+		if err := f.compactURLs(); err != nil {
+			return err
+		}
+	}
+
+	clicks := 0
+	for _, events := range f.clicks {
+		clicks += len(events)
+	}
+	if f.clicksLog.lines > clicks {
+		return f.clicksLog.rewrite(func(encoder *json.Encoder) (int, error) {
+			for _, events := range f.clicks {
+				for _, event := range events {
+					if err := encoder.Encode(event); err != nil {
+						return 0, err
+					}
+				}
+			}
+			return clicks, nil
+		})
+	}
+	return nil
+}
+
+// compactURLs rewrites the URL log from memory. The caller must hold the write lock.
+func (f *FileStorage) compactURLs() error {
+	return f.urlLog.rewrite(func(encoder *json.Encoder) (int, error) {
+		if f.counter > 0 {
+			if err := encoder.Encode(counterEntry{Counter: f.counter}); err != nil {
+				return 0, err
+			}
+		}
+		for _, record := range f.urls {
+			if err := encoder.Encode(logEntry{URLRecord: record}); err != nil {
+				return 0, err
+			}
+		}
+		return len(f.urls) + f.counterLines(), nil
+	})
+}
+
+// counterLines is the number of counter lines a compacted log holds.
+func (f *FileStorage) counterLines() int {
+	if f.counter > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (f *FileStorage) CreateShortURL(
+	_ context.Context,
+	uuid, shortURL, originalURL, userID string,
+	limits URLLimits,
+) (string, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	existingShortURL, _ := f.GetShortURLByOriginal(originalURL)
-	if existingShortURL != "" {
-		return existingShortURL, errors.New("URL already exists")
+	if existingShortURL, exists := f.byOriginal[originalURL]; exists {
+		return existingShortURL, &DuplicateURLError{ShortURL: existingShortURL}
 	}
 
-	f.urls[shortURL] = originalURL
-	if err := f.saveToFile(); err != nil {
+	if _, exists := f.urls[shortURL]; exists {
+		return "", ErrShortURLTaken
+	}
+
+	record := URLRecord{
+		UUID:        uuid,
+		ShortURL:    shortURL,
+		OriginalURL: originalURL,
+		UserID:      userID,
+		URLLimits:   limits,
+	}
+	if err := f.urlLog.append(logEntry{URLRecord: record}); err != nil {
 		return "", err
 	}
+	f.put(record)
 	return shortURL, nil
 }
 
-func (f *FileStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOutput, error) {
+func (f *FileStorage) CreateBatchURLs(_ context.Context, urls []BatchURLRequest) ([]BatchURLOutput, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if err := checkBatch(urls, f.urls, f.byOriginal); err != nil {
+		return nil, err
+	}
+
+	entries := make([]any, 0, len(urls))
 	var output []BatchURLOutput
 	for _, url := range urls {
-		if _, exists := f.urls[url.ShortURL]; exists {
-			return nil, errors.New("duplicate short URL")
-		}
-		f.urls[url.ShortURL] = url.OriginalURL
+		entries = append(entries, logEntry{URLRecord: URLRecord{
+			UUID:        url.UUID,
+			ShortURL:    url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			UserID:      url.UserID,
+			DeletedFlag: url.Deleted,
+			Clicks:      url.Clicks,
+			URLLimits:   url.URLLimits,
+		}})
 		output = append(output, BatchURLOutput{
 			CorrelationID: url.UUID,
 			ShortURL:      f.filePath + "/" + url.ShortURL,
 		})
 	}
 
-	if err := f.saveToFile(); err != nil {
+	if err := f.urlLog.append(entries...); err != nil {
 		return nil, err
 	}
+	for _, entry := range entries {
+		f.put(entry.(logEntry).URLRecord)
+	}
 
 	return output, nil
 }
 
+// GetShortURLByOriginal returns the short URL stored for originalURL, or an
+// empty string if there is none.
 func (f *FileStorage) GetShortURLByOriginal(originalURL string) (string, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
+	return f.byOriginal[originalURL], nil
+}
 
-	for short, original := range f.urls {
-		if original == originalURL {
-			return short, nil
-		}
+// GetOriginalURL resolves a short URL for a visit. Visits to URLs with a
+// click limit are counted here so the limit holds under concurrent requests.
+func (f *FileStorage) GetOriginalURL(_ context.Context, shortURL string) (string, error) {
+	f.mu.RLock()
+	record, err := f.resolvable(shortURL)
+	f.mu.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	if record.MaxClicks == 0 {
+		return record.OriginalURL, nil
 	}
-	return "", nil
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record, err = f.resolvable(shortURL)
+	if err != nil {
+		return "", err
+	}
+	record.Clicks++
+	if err := f.urlLog.append(logEntry{URLRecord: record}); err != nil {
+		return "", err
+	}
+	f.urls[shortURL] = record
+	return record.OriginalURL, nil
 }
 
-func (f *FileStorage) GetOriginalURL(shortURL string) (string, error) {
+func (f *FileStorage) LookupURL(_ context.Context, shortURL string) (URLRecord, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
+	return f.resolvable(shortURL)
+}
 
-	originalURL, exists := f.urls[shortURL]
+// resolvable returns the record if it can still be visited. The caller must hold the lock.
+func (f *FileStorage) resolvable(shortURL string) (URLRecord, error) {
+	record, exists := f.urls[shortURL]
 	if !exists {
-		return "", errors.New("URL not found")
+		return URLRecord{}, ErrNotFound
+	}
+	if record.DeletedFlag {
+		return URLRecord{}, ErrDeleted
+	}
+	if record.Expired(record.Clicks, time.Now()) {
+		return URLRecord{}, ErrExpired
 	}
-	return originalURL, nil
+	return record, nil
 }
 
-func (f *FileStorage) Ping() error {
+func (f *FileStorage) GetUserURLs(_ context.Context, userID string) ([]URLRecord, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	records := make([]URLRecord, 0, len(f.userURLs[userID]))
+	for _, short := range f.userURLs[userID] {
+		if record := f.urls[short]; !record.DeletedFlag {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (f *FileStorage) ListURLs(_ context.Context, after string, limit int) ([]URLRecord, error) {
+	// Listing may sort the code index, so it takes the write lock.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.codes.page(f.urls, after, limit), nil
+}
+
+func (f *FileStorage) DeleteURLs(_ context.Context, owned map[string][]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var entries []any
+	for userID, shortURLs := range owned {
+		for _, short := range shortURLs {
+			record, exists := f.urls[short]
+			if !exists || record.UserID != userID || record.DeletedFlag {
+				continue
+			}
+			record.DeletedFlag = true
+			entries = append(entries, logEntry{URLRecord: record})
+		}
+	}
+
+	if err := f.urlLog.append(entries...); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		f.markDeleted(entry.(logEntry).URLRecord)
+	}
+	return nil
+}
+
+func (f *FileStorage) RecordClicks(_ context.Context, events []ClickEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries := make([]any, 0, len(events))
+	for _, event := range events {
+		entries = append(entries, event)
+	}
+	if err := f.clicksLog.append(entries...); err != nil {
+		return err
+	}
+	for _, event := range events {
+		f.clicks[event.ShortURL] = append(f.clicks[event.ShortURL], event)
+	}
+	return nil
+}
+
+func (f *FileStorage) ListClicks(_ context.Context, from, to string) ([]ClickEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return clicksInRange(f.clicks, from, to), nil
+}
+
+func (f *FileStorage) GetURLStats(_ context.Context, shortURL string) (URLStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if _, exists := f.urls[shortURL]; !exists {
+		return URLStats{}, ErrNotFound
+	}
+	return aggregateClicks(f.clicks[shortURL]), nil
+}
+
+// PurgeExpired removes URLs whose expiry time or click limit has been reached.
+// Their click events are dropped from the clicks log on the next compaction.
+func (f *FileStorage) PurgeExpired(_ context.Context) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	var expired []any
+	for _, record := range f.urls {
+		if record.Expired(record.Clicks, now) {
+			expired = append(expired, logEntry{URLRecord: record, Removed: true})
+		}
+	}
+
+	if err := f.urlLog.append(expired...); err != nil {
+		return 0, err
+	}
+	for _, entry := range expired {
+		record := entry.(logEntry).URLRecord
+		f.remove(record)
+		delete(f.clicks, record.ShortURL)
+	}
+	return len(expired), nil
+}
+
+// CountURLs returns the number of URLs that have not been deleted.
+func (f *FileStorage) CountURLs(_ context.Context) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.urls) - f.deleted, nil
+}
+
+func (f *FileStorage) ReserveCounter(_ context.Context, n uint64) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	first := f.counter
+	if err := f.urlLog.append(counterEntry{Counter: first + n}); err != nil {
+		return 0, err
+	}
+	f.counter = first + n
+	return first, nil
+}
+
+func (f *FileStorage) AdvanceCounter(_ context.Context, atLeast uint64) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if atLeast <= f.counter {
+		return f.counter, nil
+	}
+	if err := f.urlLog.append(counterEntry{Counter: atLeast}); err != nil {
+		return 0, err
+	}
+	f.counter = atLeast
+	return f.counter, nil
+}
+
+// CountUsers returns the number of users that own at least one URL that has
+// not been deleted.
+func (f *FileStorage) CountUsers(_ context.Context) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.live.owners, nil
+}
+
+// put stores the record and indexes it by owner and, unless it is deleted, by
+// original URL. The caller must hold the write lock.
+func (f *FileStorage) put(record URLRecord) {
+	if _, exists := f.urls[record.ShortURL]; !exists {
+		f.codes.add(record.ShortURL)
+	}
+	f.urls[record.ShortURL] = record
+	if record.DeletedFlag {
+		f.deleted++
+	} else {
+		f.byOriginal[record.OriginalURL] = record.ShortURL
+		f.live.add(record.UserID)
+	}
+	if record.UserID != "" {
+		f.userURLs[record.UserID] = append(f.userURLs[record.UserID], record.ShortURL)
+	}
+}
+
+// markDeleted flags the stored record as deleted and drops it from the
+// indexes of live records, so its original URL can be shortened again. The
+// caller must hold the write lock.
+func (f *FileStorage) markDeleted(record URLRecord) {
+	record.DeletedFlag = true
+	f.urls[record.ShortURL] = record
+	f.deleted++
+	f.live.drop(record.UserID)
+	if f.byOriginal[record.OriginalURL] == record.ShortURL {
+		delete(f.byOriginal, record.OriginalURL)
+	}
+}
+
+// remove drops the record and its index entries. The caller must hold the write lock.
+func (f *FileStorage) remove(record URLRecord) {
+	delete(f.urls, record.ShortURL)
+	f.codes.reset()
+	if f.byOriginal[record.OriginalURL] == record.ShortURL {
+		delete(f.byOriginal, record.OriginalURL)
+	}
+	if record.DeletedFlag {
+		f.deleted--
+	} else {
+		f.live.drop(record.UserID)
+	}
+	if record.UserID == "" {
+		return
+	}
+
+	owned := f.userURLs[record.UserID]
+	for i, short := range owned {
+		if short == record.ShortURL {
+			owned = append(owned[:i], owned[i+1:]...)
+			break
+		}
+	}
+	if len(owned) == 0 {
+		delete(f.userURLs, record.UserID)
+	} else {
+		f.userURLs[record.UserID] = owned
+	}
+}
+
+func (f *FileStorage) Ping(_ context.Context) error {
 	return nil
 }
 
+// Close stops background work and flushes both logs to disk.
 func (f *FileStorage) Close() error {
-	log.Println("Closing FileStorage and saving to file")
-	return f.saveToFile()
+	f.stopOnce.Do(func() { close(f.stop) })
+	f.wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return errors.Join(f.urlLog.close(), f.clicksLog.close())
 }
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// SyncPolicy controls when appended log lines are flushed to disk with fsync.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every write; nothing acknowledged is lost on a crash.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs in the background about once a second.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+// jsonlLog is an append-only file of JSON lines. It is not safe for
+// concurrent use; FileStorage serializes access with its mutex.
+type jsonlLog struct {
+	path   string
+	file   *os.File
+	policy SyncPolicy
+	lines  int  // lines currently in the file, live or stale
+	dirty  bool // written since the last fsync
+}
+
+func openJSONLLog(path string, policy SyncPolicy) (*jsonlLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlLog{path: path, file: file, policy: policy}, nil
+}
+
+// replay passes every line to fn. Lines fn rejects are skipped, except an
+// unterminated last line, which is the remains of an interrupted write and is
+// cut off so later appends start on a clean line.
+func (l *jsonlLog) replay(fn func(line []byte) error) error {
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(l.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		terminated := err == nil
+
+		if content := bytes.TrimSpace(line); len(content) > 0 {
+			if fnErr := fn(content); fnErr != nil {
+				if !terminated {
+					log.Printf("Truncating incomplete last line of %s: %v", l.path, fnErr)
+					return l.file.Truncate(offset)
+				}
+				log.Printf("Skipping malformed line in %s: %v", l.path, fnErr)
+			} else {
+				l.lines++
+			}
+			if !terminated {
+				// A complete record without a newline, e.g. from an older format.
+				_, err := l.file.Write([]byte("\n"))
+				return err
+			}
+		}
+
+		if !terminated {
+			return nil
+		}
+		offset += int64(len(line))
+	}
+}
+
+// append writes values as JSON lines in a single write call.
+func (l *jsonlLog) append(values ...any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+	}
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", l.path, err)
+	}
+	l.lines += len(values)
+
+	if l.policy == SyncAlways {
+		return l.file.Sync()
+	}
+	l.dirty = true
+	return nil
+}
+
+// sync flushes writes made since the last sync.
+func (l *jsonlLog) sync() error {
+	if !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.file.Sync()
+}
+
+// rewrite replaces the log with the lines produced by write. The new content
+// goes to a temporary file that is renamed over the log, so a crash leaves
+// either the old or the new file intact. The file is opened for appending
+// before the rename, so a failure leaves the log writing to the old file.
+func (l *jsonlLog) rewrite(write func(encoder *json.Encoder) (int, error)) error {
+	dir := filepath.Dir(l.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(l.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	lines, err := write(json.NewEncoder(writer))
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write compacted %s: %w", l.path, err)
+	}
+
+	file, err := os.OpenFile(tmp.Name(), os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		file.Close()
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+
+	l.file.Close()
+	l.file = file
+	l.lines = lines
+	l.dirty = false
+	return nil
+}
+
+func (l *jsonlLog) close() error {
+	err := l.file.Sync()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
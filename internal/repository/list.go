@@ -0,0 +1,73 @@
+package repository
+
+import "sort"
+
+// codeIndex keeps the short codes of an in-process backend in byte order
+// for listing. New codes sorting after the last one are appended; any other
+// change drops the order, and the next listing sorts once. A walk over a
+// storage taking no writes, or only in-order inserts like a copy destination,
+// therefore sorts at most once. The caller must hold the storage's write lock.
+type codeIndex struct {
+	codes  []string
+	sorted bool
+}
+
+// add indexes a new short code.
+func (x *codeIndex) add(code string) {
+	if x.sorted && (len(x.codes) == 0 || code > x.codes[len(x.codes)-1]) {
+		x.codes = append(x.codes, code)
+		return
+	}
+	x.reset()
+}
+
+// reset drops the order, after a short code was removed or indexed out of order.
+func (x *codeIndex) reset() {
+	x.codes, x.sorted = nil, false
+}
+
+// page returns up to limit records of urls ordered by short URL and starting
+// after the given one.
+func (x *codeIndex) page(urls map[string]URLRecord, after string, limit int) []URLRecord {
+	if !x.sorted {
+		x.codes = make([]string, 0, len(urls))
+		for short := range urls {
+			x.codes = append(x.codes, short)
+		}
+		sort.Strings(x.codes)
+		x.sorted = true
+	}
+
+	start := sort.SearchStrings(x.codes, after)
+	if start < len(x.codes) && x.codes[start] == after {
+		start++
+	}
+	shortURLs := x.codes[start:]
+	if limit >= 0 && len(shortURLs) > limit {
+		shortURLs = shortURLs[:limit]
+	}
+
+	records := make([]URLRecord, len(shortURLs))
+	for i, short := range shortURLs {
+		records[i] = urls[short]
+	}
+	return records
+}
+
+// clicksInRange returns the events of short URLs in (from, to] ordered by
+// short URL and time, for the in-process backends.
+func clicksInRange(clicks map[string][]ClickEvent, from, to string) []ClickEvent {
+	var events []ClickEvent
+	for short, urlEvents := range clicks {
+		if short > from && short <= to {
+			events = append(events, urlEvents...)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].ShortURL != events[j].ShortURL {
+			return events[i].ShortURL < events[j].ShortURL
+		}
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events
+}
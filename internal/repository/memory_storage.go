@@ -1,55 +1,85 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"sync"
+	"time"
 )
 
 type InMemoryStorage struct {
-	mu   sync.RWMutex
-	urls map[string]string
+	mu         sync.RWMutex
+	urls       map[string]URLRecord
+	byOriginal map[string]string       // originalURL -> shortURL
+	userURLs   map[string][]string     // userID -> shortURLs
+	clicks     map[string][]ClickEvent // shortURL -> click events
+	deleted    int                     // soft-deleted records, kept so counts need no scan
+	live       liveOwners              // owners of records not deleted, kept so counts need no scan
+	codes      codeIndex               // short URLs in byte order, for listing
+	counter    uint64                  // next short-code counter value
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
-	return &InMemoryStorage{urls: make(map[string]string)}
+	return &InMemoryStorage{
+		urls:       make(map[string]URLRecord),
+		byOriginal: make(map[string]string),
+		userURLs:   make(map[string][]string),
+		clicks:     make(map[string][]ClickEvent),
+	}
 }
 
-func (m *InMemoryStorage) CreateShortURL(_, shortURL, originalURL string) (string, error) {
+func (m *InMemoryStorage) CreateShortURL(
+	_ context.Context,
+	uuid, shortURL, originalURL, userID string,
+	limits URLLimits,
+) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	existingShortURL, _ := m.GetShortURLByOriginal(originalURL)
-	if existingShortURL != "" {
-		return existingShortURL, errors.New("URL already exists")
+	if existingShortURL, exists := m.byOriginal[originalURL]; exists {
+		return existingShortURL, &DuplicateURLError{ShortURL: existingShortURL}
+	}
+
+	if _, exists := m.urls[shortURL]; exists {
+		return "", ErrShortURLTaken
 	}
 
-	m.urls[shortURL] = originalURL
+	m.put(URLRecord{
+		UUID:        uuid,
+		ShortURL:    shortURL,
+		OriginalURL: originalURL,
+		UserID:      userID,
+		URLLimits:   limits,
+	})
 	return shortURL, nil
 }
 
+// GetShortURLByOriginal returns the short URL stored for originalURL, or an
+// empty string if there is none.
 func (m *InMemoryStorage) GetShortURLByOriginal(originalURL string) (string, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-
-	for short, original := range m.urls {
-		if original == originalURL {
-			return short, nil
-		}
-	}
-	return "", nil
+	return m.byOriginal[originalURL], nil
 }
 
-func (m *InMemoryStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOutput, error) {
+func (m *InMemoryStorage) CreateBatchURLs(_ context.Context, urls []BatchURLRequest) ([]BatchURLOutput, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var output []BatchURLOutput
+	if err := checkBatch(urls, m.urls, m.byOriginal); err != nil {
+		return nil, err
+	}
 
+	var output []BatchURLOutput
 	for _, url := range urls {
-		if _, exists := m.urls[url.ShortURL]; exists {
-			return nil, errors.New("duplicate short URL")
-		}
-		m.urls[url.ShortURL] = url.OriginalURL
+		m.put(URLRecord{
+			UUID:        url.UUID,
+			ShortURL:    url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			UserID:      url.UserID,
+			DeletedFlag: url.Deleted,
+			Clicks:      url.Clicks,
+			URLLimits:   url.URLLimits,
+		})
 		output = append(output, BatchURLOutput{
 			CorrelationID: url.UUID,
 			ShortURL:      "http://localhost:8080/" + url.ShortURL,
@@ -59,18 +89,225 @@ func (m *InMemoryStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOut
 	return output, nil
 }
 
-func (m *InMemoryStorage) GetOriginalURL(shortURL string) (string, error) {
+// GetOriginalURL resolves a short URL for a visit. Visits to URLs with a
+// click limit are counted here so the limit holds under concurrent requests.
+func (m *InMemoryStorage) GetOriginalURL(_ context.Context, shortURL string) (string, error) {
+	m.mu.RLock()
+	record, err := m.resolvable(shortURL)
+	m.mu.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	if record.MaxClicks == 0 {
+		return record.OriginalURL, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, err = m.resolvable(shortURL)
+	if err != nil {
+		return "", err
+	}
+	record.Clicks++
+	m.urls[shortURL] = record
+	return record.OriginalURL, nil
+}
+
+func (m *InMemoryStorage) LookupURL(_ context.Context, shortURL string) (URLRecord, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.resolvable(shortURL)
+}
 
-	originalURL, exists := m.urls[shortURL]
+// resolvable returns the record if it can still be visited. The caller must hold the lock.
+func (m *InMemoryStorage) resolvable(shortURL string) (URLRecord, error) {
+	record, exists := m.urls[shortURL]
 	if !exists {
-		return "", errors.New("URL not found")
+		return URLRecord{}, ErrNotFound
+	}
+	if record.DeletedFlag {
+		return URLRecord{}, ErrDeleted
+	}
+	if record.Expired(record.Clicks, time.Now()) {
+		return URLRecord{}, ErrExpired
+	}
+	return record, nil
+}
+
+func (m *InMemoryStorage) GetUserURLs(_ context.Context, userID string) ([]URLRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make([]URLRecord, 0, len(m.userURLs[userID]))
+	for _, short := range m.userURLs[userID] {
+		if record := m.urls[short]; !record.DeletedFlag {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (m *InMemoryStorage) ListURLs(_ context.Context, after string, limit int) ([]URLRecord, error) {
+	// Listing may sort the code index, so it takes the write lock.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.codes.page(m.urls, after, limit), nil
+}
+
+func (m *InMemoryStorage) DeleteURLs(_ context.Context, owned map[string][]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, shortURLs := range owned {
+		for _, short := range shortURLs {
+			record, exists := m.urls[short]
+			if !exists || record.UserID != userID || record.DeletedFlag {
+				continue
+			}
+			m.markDeleted(record)
+		}
+	}
+	return nil
+}
+
+func (m *InMemoryStorage) RecordClicks(_ context.Context, events []ClickEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, event := range events {
+		m.clicks[event.ShortURL] = append(m.clicks[event.ShortURL], event)
+	}
+	return nil
+}
+
+func (m *InMemoryStorage) ListClicks(_ context.Context, from, to string) ([]ClickEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return clicksInRange(m.clicks, from, to), nil
+}
+
+func (m *InMemoryStorage) GetURLStats(_ context.Context, shortURL string) (URLStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.urls[shortURL]; !exists {
+		return URLStats{}, ErrNotFound
+	}
+	return aggregateClicks(m.clicks[shortURL]), nil
+}
+
+// PurgeExpired removes URLs whose expiry time or click limit has been reached.
+func (m *InMemoryStorage) PurgeExpired(_ context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	purged := 0
+	for short, record := range m.urls {
+		if !record.Expired(record.Clicks, now) {
+			continue
+		}
+		m.remove(record)
+		delete(m.clicks, short)
+		purged++
+	}
+
+	return purged, nil
+}
+
+// CountURLs returns the number of URLs that have not been deleted.
+func (m *InMemoryStorage) CountURLs(_ context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.urls) - m.deleted, nil
+}
+
+func (m *InMemoryStorage) ReserveCounter(_ context.Context, n uint64) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	first := m.counter
+	m.counter += n
+	return first, nil
+}
+
+func (m *InMemoryStorage) AdvanceCounter(_ context.Context, atLeast uint64) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counter = max(m.counter, atLeast)
+	return m.counter, nil
+}
+
+// CountUsers returns the number of users that own at least one URL that has
+// not been deleted.
+func (m *InMemoryStorage) CountUsers(_ context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.live.owners, nil
+}
+
+// put stores the record and indexes it by owner and, unless it is deleted, by
+// original URL. The caller must hold the write lock.
+func (m *InMemoryStorage) put(record URLRecord) {
+	if _, exists := m.urls[record.ShortURL]; !exists {
+		m.codes.add(record.ShortURL)
+	}
+	m.urls[record.ShortURL] = record
+	if record.DeletedFlag {
+		m.deleted++
+	} else {
+		m.byOriginal[record.OriginalURL] = record.ShortURL
+		m.live.add(record.UserID)
+	}
+	if record.UserID != "" {
+		m.userURLs[record.UserID] = append(m.userURLs[record.UserID], record.ShortURL)
+	}
+}
+
+// markDeleted flags the stored record as deleted and drops it from the
+// indexes of live records, so its original URL can be shortened again. The
+// caller must hold the write lock.
+func (m *InMemoryStorage) markDeleted(record URLRecord) {
+	record.DeletedFlag = true
+	m.urls[record.ShortURL] = record
+	m.deleted++
+	m.live.drop(record.UserID)
+	if m.byOriginal[record.OriginalURL] == record.ShortURL {
+		delete(m.byOriginal, record.OriginalURL)
+	}
+}
+
+// remove drops the record and its index entries. The caller must hold the write lock.
+func (m *InMemoryStorage) remove(record URLRecord) {
+	delete(m.urls, record.ShortURL)
+	m.codes.reset()
+	if m.byOriginal[record.OriginalURL] == record.ShortURL {
+		delete(m.byOriginal, record.OriginalURL)
+	}
+	if record.DeletedFlag {
+		m.deleted--
+	} else {
+		m.live.drop(record.UserID)
+	}
+	if record.UserID == "" {
+		return
+	}
+
+	owned := m.userURLs[record.UserID]
+	for i, short := range owned {
+		if short == record.ShortURL {
+			owned = append(owned[:i], owned[i+1:]...)
+			break
+		}
+	}
+	if len(owned) == 0 {
+		delete(m.userURLs, record.UserID)
+	} else {
+		m.userURLs[record.UserID] = owned
 	}
-	return originalURL, nil
 }
 
-func (m *InMemoryStorage) Ping() error {
+func (m *InMemoryStorage) Ping(_ context.Context) error {
 	return nil
 }
 
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS shortened_urls (
+    uuid UUID PRIMARY KEY,
+    short_url VARCHAR(255) UNIQUE NOT NULL,
+    original_url TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS shortened_urls_user_id_idx;
+ALTER TABLE shortened_urls DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,2 @@
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS user_id VARCHAR(36);
+CREATE INDEX IF NOT EXISTS shortened_urls_user_id_idx ON shortened_urls (user_id);
@@ -0,0 +1 @@
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS shortened_urls_expires_at_idx;
+ALTER TABLE shortened_urls DROP COLUMN IF EXISTS clicks;
+ALTER TABLE shortened_urls DROP COLUMN IF EXISTS max_clicks;
+ALTER TABLE shortened_urls DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,4 @@
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS max_clicks INTEGER;
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS clicks INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS shortened_urls_expires_at_idx ON shortened_urls (expires_at);
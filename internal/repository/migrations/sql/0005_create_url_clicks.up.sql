@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS url_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_url VARCHAR(255) NOT NULL,
+    clicked_at TIMESTAMP NOT NULL,
+    referrer TEXT,
+    user_agent TEXT,
+    ip_hash VARCHAR(64)
+);
+CREATE INDEX IF NOT EXISTS url_clicks_short_url_idx ON url_clicks (short_url, clicked_at);
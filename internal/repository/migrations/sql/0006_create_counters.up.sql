@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS counters (
+    name VARCHAR(64) PRIMARY KEY,
+    value BIGINT NOT NULL
+);
+INSERT INTO counters (name, value) VALUES ('short_code', 0) ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS shortened_urls_short_url_c_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS shortened_urls_short_url_c_idx ON shortened_urls (short_url COLLATE "C");
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS url_clicks_short_url_c_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS url_clicks_short_url_c_idx ON url_clicks (short_url COLLATE "C", clicked_at);
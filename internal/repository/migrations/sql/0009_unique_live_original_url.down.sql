@@ -0,0 +1,3 @@
+-- Fails once a deleted URL has been shortened again.
+DROP INDEX IF EXISTS shortened_urls_live_original_url_key;
+ALTER TABLE shortened_urls ADD CONSTRAINT shortened_urls_original_url_key UNIQUE (original_url);
@@ -0,0 +1,3 @@
+-- Deleted URLs may be shortened again, so only live rows must be unique.
+ALTER TABLE shortened_urls DROP CONSTRAINT IF EXISTS shortened_urls_original_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS shortened_urls_live_original_url_key ON shortened_urls (original_url) WHERE NOT is_deleted;
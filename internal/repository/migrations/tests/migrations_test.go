@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/repository/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	loaded, err := migrations.Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, m := range loaded {
+		if m.Version != int64(i+1) {
+			t.Errorf("Expected version %d at position %d, got %d", i+1, i, m.Version)
+		}
+		if m.Name == "" || m.Up == "" || m.Down == "" {
+			t.Errorf("Migration %d is incomplete: %+v", m.Version, m)
+		}
+	}
+}
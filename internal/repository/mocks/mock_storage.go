@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,6 +35,21 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// AdvanceCounter mocks base method.
+func (m *MockStorage) AdvanceCounter(ctx context.Context, atLeast uint64) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceCounter", ctx, atLeast)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceCounter indicates an expected call of AdvanceCounter.
+func (mr *MockStorageMockRecorder) AdvanceCounter(ctx, atLeast interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceCounter", reflect.TypeOf((*MockStorage)(nil).AdvanceCounter), ctx, atLeast)
+}
+
 // Close mocks base method.
 func (m *MockStorage) Close() error {
 	m.ctrl.T.Helper()
@@ -48,61 +64,224 @@ func (mr *MockStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStorage)(nil).Close))
 }
 
+// CountURLs mocks base method.
+func (m *MockStorage) CountURLs(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLs", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLs indicates an expected call of CountURLs.
+func (mr *MockStorageMockRecorder) CountURLs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLs", reflect.TypeOf((*MockStorage)(nil).CountURLs), ctx)
+}
+
+// CountUsers mocks base method.
+func (m *MockStorage) CountUsers(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockStorageMockRecorder) CountUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockStorage)(nil).CountUsers), ctx)
+}
+
 // CreateBatchURLs mocks base method.
-func (m *MockStorage) CreateBatchURLs(urls []repository.BatchURLRequest) ([]repository.BatchURLOutput, error) {
+func (m *MockStorage) CreateBatchURLs(ctx context.Context, urls []repository.BatchURLRequest) ([]repository.BatchURLOutput, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateBatchURLs", urls)
+	ret := m.ctrl.Call(m, "CreateBatchURLs", ctx, urls)
 	ret0, _ := ret[0].([]repository.BatchURLOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateBatchURLs indicates an expected call of CreateBatchURLs.
-func (mr *MockStorageMockRecorder) CreateBatchURLs(urls interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) CreateBatchURLs(ctx, urls interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatchURLs", reflect.TypeOf((*MockStorage)(nil).CreateBatchURLs), urls)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatchURLs", reflect.TypeOf((*MockStorage)(nil).CreateBatchURLs), ctx, urls)
 }
 
 // CreateShortURL mocks base method.
-func (m *MockStorage) CreateShortURL(uuid, shortURL, originalURL string) (string, error) {
+func (m *MockStorage) CreateShortURL(ctx context.Context, uuid, shortURL, originalURL, userID string, limits repository.URLLimits) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateShortURL", uuid, shortURL, originalURL)
+	ret := m.ctrl.Call(m, "CreateShortURL", ctx, uuid, shortURL, originalURL, userID, limits)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateShortURL indicates an expected call of CreateShortURL.
-func (mr *MockStorageMockRecorder) CreateShortURL(uuid, shortURL, originalURL interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) CreateShortURL(ctx, uuid, shortURL, originalURL, userID, limits interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShortURL", reflect.TypeOf((*MockStorage)(nil).CreateShortURL), uuid, shortURL, originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShortURL", reflect.TypeOf((*MockStorage)(nil).CreateShortURL), ctx, uuid, shortURL, originalURL, userID, limits)
+}
+
+// DeleteURLs mocks base method.
+func (m *MockStorage) DeleteURLs(ctx context.Context, owned map[string][]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteURLs", ctx, owned)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteURLs indicates an expected call of DeleteURLs.
+func (mr *MockStorageMockRecorder) DeleteURLs(ctx, owned interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURLs", reflect.TypeOf((*MockStorage)(nil).DeleteURLs), ctx, owned)
 }
 
 // GetOriginalURL mocks base method.
-func (m *MockStorage) GetOriginalURL(shortURL string) (string, error) {
+func (m *MockStorage) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOriginalURL", shortURL)
+	ret := m.ctrl.Call(m, "GetOriginalURL", ctx, shortURL)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOriginalURL indicates an expected call of GetOriginalURL.
-func (mr *MockStorageMockRecorder) GetOriginalURL(shortURL interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetOriginalURL(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockStorage)(nil).GetOriginalURL), ctx, shortURL)
+}
+
+// GetURLStats mocks base method.
+func (m *MockStorage) GetURLStats(ctx context.Context, shortURL string) (repository.URLStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLStats", ctx, shortURL)
+	ret0, _ := ret[0].(repository.URLStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLStats indicates an expected call of GetURLStats.
+func (mr *MockStorageMockRecorder) GetURLStats(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLStats", reflect.TypeOf((*MockStorage)(nil).GetURLStats), ctx, shortURL)
+}
+
+// GetUserURLs mocks base method.
+func (m *MockStorage) GetUserURLs(ctx context.Context, userID string) ([]repository.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserURLs", ctx, userID)
+	ret0, _ := ret[0].([]repository.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserURLs indicates an expected call of GetUserURLs.
+func (mr *MockStorageMockRecorder) GetUserURLs(ctx, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockStorage)(nil).GetOriginalURL), shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockStorage)(nil).GetUserURLs), ctx, userID)
+}
+
+// ListClicks mocks base method.
+func (m *MockStorage) ListClicks(ctx context.Context, from, to string) ([]repository.ClickEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClicks", ctx, from, to)
+	ret0, _ := ret[0].([]repository.ClickEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClicks indicates an expected call of ListClicks.
+func (mr *MockStorageMockRecorder) ListClicks(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClicks", reflect.TypeOf((*MockStorage)(nil).ListClicks), ctx, from, to)
+}
+
+// ListURLs mocks base method.
+func (m *MockStorage) ListURLs(ctx context.Context, after string, limit int) ([]repository.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, after, limit)
+	ret0, _ := ret[0].([]repository.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockStorageMockRecorder) ListURLs(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockStorage)(nil).ListURLs), ctx, after, limit)
+}
+
+// LookupURL mocks base method.
+func (m *MockStorage) LookupURL(ctx context.Context, shortURL string) (repository.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupURL", ctx, shortURL)
+	ret0, _ := ret[0].(repository.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupURL indicates an expected call of LookupURL.
+func (mr *MockStorageMockRecorder) LookupURL(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupURL", reflect.TypeOf((*MockStorage)(nil).LookupURL), ctx, shortURL)
 }
 
 // Ping mocks base method.
-func (m *MockStorage) Ping() error {
+func (m *MockStorage) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockStorageMockRecorder) Ping() *gomock.Call {
+func (mr *MockStorageMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping), ctx)
+}
+
+// PurgeExpired mocks base method.
+func (m *MockStorage) PurgeExpired(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockStorageMockRecorder) PurgeExpired(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockStorage)(nil).PurgeExpired), ctx)
+}
+
+// RecordClicks mocks base method.
+func (m *MockStorage) RecordClicks(ctx context.Context, events []repository.ClickEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordClicks", ctx, events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordClicks indicates an expected call of RecordClicks.
+func (mr *MockStorageMockRecorder) RecordClicks(ctx, events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClicks", reflect.TypeOf((*MockStorage)(nil).RecordClicks), ctx, events)
+}
+
+// ReserveCounter mocks base method.
+func (m *MockStorage) ReserveCounter(ctx context.Context, n uint64) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveCounter", ctx, n)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveCounter indicates an expected call of ReserveCounter.
+func (mr *MockStorageMockRecorder) ReserveCounter(ctx, n interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveCounter", reflect.TypeOf((*MockStorage)(nil).ReserveCounter), ctx, n)
 }
@@ -4,68 +4,100 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/hairutdin/url-shortener/internal/repository/migrations"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-const createTableQuery = `
-CREATE TABLE IF NOT EXISTS shortened_urls (
-    uuid UUID PRIMARY KEY,
-    short_url VARCHAR(255) UNIQUE NOT NULL,
-    original_url TEXT UNIQUE NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);`
+const shortURLUniqueConstraint = "shortened_urls_short_url_key"
 
 type PostgresStorage struct {
-	DB *pgx.Conn
+	DB *pgxpool.Pool
 }
 
-func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
-	DB, err := pgx.Connect(context.Background(), dsn)
+// NewPostgresStorage opens a connection pool of at most maxConns connections;
+// zero keeps the pgxpool default. With autoMigrate set, pending schema
+// migrations are applied before the storage is returned.
+func NewPostgresStorage(ctx context.Context, dsn string, maxConns int32, autoMigrate bool) (*PostgresStorage, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN: %w", err)
+	}
+	if maxConns > 0 {
+		poolConfig.MaxConns = maxConns
+	}
+
+	DB, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
-	if _, err := DB.Exec(context.Background(), createTableQuery); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
+	if autoMigrate {
+		if err := migrate(ctx, DB); err != nil {
+			DB.Close()
+			return nil, err
+		}
 	}
 
 	return &PostgresStorage{DB: DB}, nil
 }
 
-func (p *PostgresStorage) CreateShortURL(uuid, shortURL, originalURL string) (string, error) {
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	migrator, err := migrations.NewMigrator(pool)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) CreateShortURL(
+	ctx context.Context,
+	uuid, shortURL, originalURL, userID string,
+	limits URLLimits,
+) (string, error) {
 	const query = `
-		INSERT INTO shortened_urls (uuid, short_url, original_url)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (original_url) DO NOTHING
+		INSERT INTO shortened_urls (uuid, short_url, original_url, user_id, expires_at, max_clicks)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, 0))
+		ON CONFLICT (original_url) WHERE NOT is_deleted DO NOTHING
 		RETURNING short_url;
 	`
 
-	var existingShortURL string
-	err := p.DB.QueryRow(context.Background(), query, uuid, shortURL, originalURL).Scan(&existingShortURL)
+	var createdShortURL string
+	err := p.DB.QueryRow(ctx, query,
+		uuid, shortURL, originalURL, userID, limits.ExpiresAt, limits.MaxClicks).Scan(&createdShortURL)
+	if err == nil {
+		return createdShortURL, nil
+	}
 
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			existingShortURL, err := p.GetShortURLByOriginal(originalURL)
-			if err != nil {
-				return "", fmt.Errorf("failed to fetch existing short URL: %w", err)
-			}
-			return existingShortURL, fmt.Errorf("URL already exists with short URL: %s", existingShortURL)
+	// ON CONFLICT DO NOTHING returns no row when the original URL exists.
+	if errors.Is(err, pgx.ErrNoRows) {
+		existingShortURL, err := p.GetShortURLByOriginal(ctx, originalURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch existing short URL: %w", err)
 		}
-		return "", err
+		return existingShortURL, &DuplicateURLError{ShortURL: existingShortURL}
 	}
-	return existingShortURL, nil
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation &&
+		pgErr.ConstraintName == shortURLUniqueConstraint {
+		return "", ErrShortURLTaken
+	}
+	return "", fmt.Errorf("failed to create short URL: %w", err)
 }
 
-func (p *PostgresStorage) GetShortURLByOriginal(originalURL string) (string, error) {
-	const query = `SELECT short_url FROM shortened_urls WHERE original_url = $1`
+func (p *PostgresStorage) GetShortURLByOriginal(ctx context.Context, originalURL string) (string, error) {
+	const query = `SELECT short_url FROM shortened_urls WHERE original_url = $1 AND NOT is_deleted`
 	var shortURL string
-	err := p.DB.QueryRow(context.Background(), query, originalURL).Scan(&shortURL)
+	err := p.DB.QueryRow(ctx, query, originalURL).Scan(&shortURL)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return "", nil
 		}
 		return "", fmt.Errorf("failed to get short URL: %w", err)
@@ -73,13 +105,13 @@ func (p *PostgresStorage) GetShortURLByOriginal(originalURL string) (string, err
 	return shortURL, nil
 }
 
-func (p *PostgresStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOutput, error) {
-	tx, err := p.DB.Begin(context.Background())
+func (p *PostgresStorage) CreateBatchURLs(ctx context.Context, urls []BatchURLRequest) ([]BatchURLOutput, error) {
+	tx, err := p.DB.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() {
-		if err := tx.Rollback(context.Background()); err != nil && err != pgx.ErrTxClosed {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
 			fmt.Printf("rollback failed: %v\n", err)
 		}
 	}()
@@ -87,10 +119,24 @@ func (p *PostgresStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOut
 	outputs := make([]BatchURLOutput, 0, len(urls))
 
 	for _, url := range urls {
-		_, err := tx.Exec(context.Background(),
-			`INSERT INTO shortened_urls (uuid, short_url, original_url) VALUES ($1, $2, $3)`,
-			url.UUID, url.ShortURL, url.OriginalURL)
+		_, err := tx.Exec(ctx,
+			`INSERT INTO shortened_urls
+				(uuid, short_url, original_url, user_id, expires_at, max_clicks, clicks, is_deleted)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, 0), $7, $8)`,
+			url.UUID, url.ShortURL, url.OriginalURL, url.UserID, url.ExpiresAt, url.MaxClicks, url.Clicks, url.Deleted)
 		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				if pgErr.ConstraintName == shortURLUniqueConstraint {
+					return nil, ErrShortURLTaken
+				}
+				// The transaction is aborted; look the existing code up on another connection.
+				existingShortURL, lookupErr := p.GetShortURLByOriginal(ctx, url.OriginalURL)
+				if lookupErr != nil {
+					return nil, fmt.Errorf("failed to fetch existing short URL: %w", lookupErr)
+				}
+				return nil, &DuplicateURLError{ShortURL: existingShortURL}
+			}
 			return nil, fmt.Errorf("failed to insert batch URL: %w", err)
 		}
 
@@ -101,25 +147,337 @@ func (p *PostgresStorage) CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOut
 		outputs = append(outputs, output)
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return outputs, nil
 }
 
-func (p *PostgresStorage) GetOriginalURL(shortURL string) (string, error) {
-	var originalURL string
-	err := p.DB.QueryRow(context.Background(),
-		"SELECT original_url FROM shortened_urls WHERE short_url=$1", shortURL).
-		Scan(&originalURL)
-	return originalURL, err
+// GetOriginalURL resolves a short URL for a visit. Visits to URLs with a
+// click limit are counted with a conditional update so the limit holds
+// under concurrent requests.
+func (p *PostgresStorage) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
+	const query = `
+		SELECT original_url, is_deleted, expires_at, COALESCE(max_clicks, 0), clicks
+		FROM shortened_urls
+		WHERE short_url = $1
+	`
+	const countQuery = `
+		UPDATE shortened_urls
+		SET clicks = clicks + 1
+		WHERE short_url = $1 AND clicks < max_clicks
+	`
+
+	var (
+		originalURL string
+		deleted     bool
+		limits      URLLimits
+		clicks      int
+	)
+	err := p.DB.QueryRow(ctx, query, shortURL).
+		Scan(&originalURL, &deleted, &limits.ExpiresAt, &limits.MaxClicks, &clicks)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get original URL: %w", err)
+	}
+	if deleted {
+		return "", ErrDeleted
+	}
+	if limits.Expired(clicks, time.Now()) {
+		return "", ErrExpired
+	}
+
+	if limits.MaxClicks > 0 {
+		tag, err := p.DB.Exec(ctx, countQuery, shortURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to count visit: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return "", ErrExpired
+		}
+	}
+	return originalURL, nil
+}
+
+func (p *PostgresStorage) LookupURL(ctx context.Context, shortURL string) (URLRecord, error) {
+	const query = `
+		SELECT uuid, short_url, original_url, COALESCE(user_id, ''), is_deleted,
+		       expires_at, COALESCE(max_clicks, 0), clicks
+		FROM shortened_urls
+		WHERE short_url = $1
+	`
+
+	var record URLRecord
+	err := p.DB.QueryRow(ctx, query, shortURL).Scan(
+		&record.UUID, &record.ShortURL, &record.OriginalURL, &record.UserID, &record.DeletedFlag,
+		&record.ExpiresAt, &record.MaxClicks, &record.Clicks,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return URLRecord{}, ErrNotFound
+		}
+		return URLRecord{}, fmt.Errorf("failed to look up URL: %w", err)
+	}
+	if record.DeletedFlag {
+		return URLRecord{}, ErrDeleted
+	}
+	if record.Expired(record.Clicks, time.Now()) {
+		return URLRecord{}, ErrExpired
+	}
+	return record, nil
+}
+
+func (p *PostgresStorage) GetUserURLs(ctx context.Context, userID string) ([]URLRecord, error) {
+	const query = `
+		SELECT uuid, short_url, original_url
+		FROM shortened_urls
+		WHERE user_id = $1 AND NOT is_deleted
+		ORDER BY created_at
+	`
+
+	rows, err := p.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user URLs: %w", err)
+	}
+	defer rows.Close()
+
+	var records []URLRecord
+	for rows.Next() {
+		record := URLRecord{UserID: userID}
+		if err := rows.Scan(&record.UUID, &record.ShortURL, &record.OriginalURL); err != nil {
+			return nil, fmt.Errorf("failed to scan user URL: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user URLs: %w", err)
+	}
+	return records, nil
+}
+
+func (p *PostgresStorage) ListURLs(ctx context.Context, after string, limit int) ([]URLRecord, error) {
+	const query = `
+		SELECT uuid, short_url, original_url, COALESCE(user_id, ''), is_deleted,
+		       expires_at, COALESCE(max_clicks, 0), clicks
+		FROM shortened_urls
+		WHERE short_url COLLATE "C" > $1
+		ORDER BY short_url COLLATE "C"
+		LIMIT $2
+	`
+
+	rows, err := p.DB.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]URLRecord, 0, limit)
+	for rows.Next() {
+		var record URLRecord
+		err := rows.Scan(
+			&record.UUID, &record.ShortURL, &record.OriginalURL, &record.UserID, &record.DeletedFlag,
+			&record.ExpiresAt, &record.MaxClicks, &record.Clicks,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URLs: %w", err)
+	}
+	return records, nil
+}
+
+func (p *PostgresStorage) DeleteURLs(ctx context.Context, owned map[string][]string) error {
+	const query = `
+		UPDATE shortened_urls AS u
+		SET is_deleted = TRUE
+		FROM unnest($1::text[], $2::text[]) AS d(short_url, user_id)
+		WHERE u.short_url = d.short_url AND u.user_id = d.user_id AND NOT u.is_deleted
+	`
+
+	var shortURLs, userIDs []string
+	for userID, owned := range owned {
+		for _, short := range owned {
+			shortURLs = append(shortURLs, short)
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(shortURLs) == 0 {
+		return nil
+	}
+	if _, err := p.DB.Exec(ctx, query, shortURLs, userIDs); err != nil {
+		return fmt.Errorf("failed to delete URLs: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) RecordClicks(ctx context.Context, events []ClickEvent) error {
+	rows := make([][]any, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []any{event.ShortURL, event.Timestamp.UTC(), event.Referrer, event.UserAgent, event.IPHash})
+	}
+
+	_, err := p.DB.CopyFrom(ctx,
+		pgx.Identifier{"url_clicks"},
+		[]string{"short_url", "clicked_at", "referrer", "user_agent", "ip_hash"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) ListClicks(ctx context.Context, from, to string) ([]ClickEvent, error) {
+	const query = `
+		SELECT short_url, clicked_at, COALESCE(referrer, ''), COALESCE(user_agent, ''), COALESCE(ip_hash, '')
+		FROM url_clicks
+		WHERE short_url COLLATE "C" > $1 AND short_url COLLATE "C" <= $2
+		ORDER BY short_url COLLATE "C", clicked_at, id
+	`
+
+	rows, err := p.DB.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ClickEvent
+	for rows.Next() {
+		var event ClickEvent
+		if err := rows.Scan(&event.ShortURL, &event.Timestamp, &event.Referrer, &event.UserAgent,
+			&event.IPHash); err != nil {
+			return nil, fmt.Errorf("failed to scan click: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list clicks: %w", err)
+	}
+	return events, nil
+}
+
+func (p *PostgresStorage) GetURLStats(ctx context.Context, shortURL string) (URLStats, error) {
+	const totalsQuery = `
+		SELECT COUNT(c.id), COUNT(DISTINCT c.ip_hash)
+		FROM shortened_urls s
+		LEFT JOIN url_clicks c ON c.short_url = s.short_url
+		WHERE s.short_url = $1
+		GROUP BY s.short_url
+	`
+	const dailyQuery = `
+		SELECT date_trunc('day', clicked_at) AS day, COUNT(*)
+		FROM url_clicks
+		WHERE short_url = $1
+		GROUP BY day
+		ORDER BY day
+	`
+
+	var stats URLStats
+	// The join yields no row only for an unknown short URL; a known URL
+	// without clicks produces a single row of zero counts.
+	err := p.DB.QueryRow(ctx, totalsQuery, shortURL).
+		Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return URLStats{}, ErrNotFound
+		}
+		return URLStats{}, fmt.Errorf("failed to get URL stats: %w", err)
+	}
+
+	rows, err := p.DB.Query(ctx, dailyQuery, shortURL)
+	if err != nil {
+		return URLStats{}, fmt.Errorf("failed to get daily clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day DailyClicks
+		if err := rows.Scan(&day.Date, &day.Clicks); err != nil {
+			return URLStats{}, fmt.Errorf("failed to scan daily clicks: %w", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return URLStats{}, fmt.Errorf("failed to read daily clicks: %w", err)
+	}
+	return stats, nil
+}
+
+// PurgeExpired removes URLs whose expiry time or click limit has been reached,
+// together with their click events.
+func (p *PostgresStorage) PurgeExpired(ctx context.Context) (int, error) {
+	const query = `
+		WITH purged AS (
+			DELETE FROM shortened_urls
+			WHERE expires_at <= now() OR clicks >= max_clicks
+			RETURNING short_url
+		), purged_clicks AS (
+			DELETE FROM url_clicks
+			WHERE short_url IN (SELECT short_url FROM purged)
+		)
+		SELECT COUNT(*) FROM purged
+	`
+
+	var purged int
+	if err := p.DB.QueryRow(ctx, query).Scan(&purged); err != nil {
+		return 0, fmt.Errorf("failed to purge expired URLs: %w", err)
+	}
+	return purged, nil
+}
+
+func (p *PostgresStorage) CountURLs(ctx context.Context) (int, error) {
+	var count int
+	err := p.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FROM shortened_urls WHERE NOT is_deleted`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count URLs: %w", err)
+	}
+	return count, nil
+}
+
+func (p *PostgresStorage) ReserveCounter(ctx context.Context, n uint64) (uint64, error) {
+	var first int64
+	err := p.DB.QueryRow(ctx,
+		`UPDATE counters SET value = value + $1 WHERE name = 'short_code' RETURNING value - $1`,
+		int64(n)).Scan(&first)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve short code counter: %w", err)
+	}
+	return uint64(first), nil
+}
+
+func (p *PostgresStorage) AdvanceCounter(ctx context.Context, atLeast uint64) (uint64, error) {
+	var value int64
+	err := p.DB.QueryRow(ctx,
+		`UPDATE counters SET value = GREATEST(value, $1) WHERE name = 'short_code' RETURNING value`,
+		int64(atLeast)).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance short code counter: %w", err)
+	}
+	return uint64(value), nil
+}
+
+func (p *PostgresStorage) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := p.DB.QueryRow(ctx,
+		`SELECT COUNT(DISTINCT user_id) FROM shortened_urls WHERE NOT is_deleted`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
 }
 
-func (p *PostgresStorage) Ping() error {
-	return p.DB.Ping(context.Background())
+func (p *PostgresStorage) Ping(ctx context.Context) error {
+	return p.DB.Ping(ctx)
 }
 
 func (p *PostgresStorage) Close() error {
-	return p.DB.Close(context.Background())
+	p.DB.Close()
+	return nil
 }
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"sort"
+	"time"
+)
+
+// aggregateClicks builds URL statistics from raw click events. It is shared by
+// the storages that keep click events in memory.
+func aggregateClicks(events []ClickEvent) URLStats {
+	visitors := make(map[string]struct{})
+	perDay := make(map[time.Time]int)
+
+	for _, event := range events {
+		if event.IPHash != "" {
+			visitors[event.IPHash] = struct{}{}
+		}
+		ts := event.Timestamp.UTC()
+		perDay[time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)]++
+	}
+
+	daily := make([]DailyClicks, 0, len(perDay))
+	for day, clicks := range perDay {
+		daily = append(daily, DailyClicks{Date: day, Clicks: clicks})
+	}
+	sort.Slice(daily, func(i, j int) bool { return daily[i].Date.Before(daily[j].Date) })
+
+	return URLStats{
+		TotalClicks:    len(events),
+		UniqueVisitors: len(visitors),
+		Daily:          daily,
+	}
+}
+
+// liveOwners counts the URLs each user owns that have not been deleted, and
+// the users owning at least one, for the storages that keep URLs in memory.
+// The zero value is ready to use; the caller must serialize access.
+type liveOwners struct {
+	urls   map[string]int // userID -> live URLs
+	owners int
+}
+
+// add counts a live URL of the user.
+func (l *liveOwners) add(userID string) {
+	if userID == "" {
+		return
+	}
+	if l.urls == nil {
+		l.urls = make(map[string]int)
+	}
+	l.urls[userID]++
+	if l.urls[userID] == 1 {
+		l.owners++
+	}
+}
+
+// drop uncounts a live URL of the user, once it is deleted or removed.
+func (l *liveOwners) drop(userID string) {
+	if userID == "" || l.urls[userID] == 0 {
+		return
+	}
+	l.urls[userID]--
+	if l.urls[userID] == 0 {
+		delete(l.urls, userID)
+		l.owners--
+	}
+}
@@ -1,9 +1,37 @@
 package repository
 
+import "context"
+
 type Storage interface {
-	CreateShortURL(uuid, shortURL, originalURL string) (string, error)
-	GetOriginalURL(shortURL string) (string, error)
-	CreateBatchURLs(urls []BatchURLRequest) ([]BatchURLOutput, error)
-	Ping() error
+	CreateShortURL(ctx context.Context, uuid, shortURL, originalURL, userID string, limits URLLimits) (string, error)
+	GetOriginalURL(ctx context.Context, shortURL string) (string, error)
+	// LookupURL returns the record of a URL that can still be resolved
+	// without counting a visit.
+	LookupURL(ctx context.Context, shortURL string) (URLRecord, error)
+	CreateBatchURLs(ctx context.Context, urls []BatchURLRequest) ([]BatchURLOutput, error)
+	GetUserURLs(ctx context.Context, userID string) ([]URLRecord, error)
+	// ListURLs returns up to limit records, deleted and expired ones
+	// included, in byte order of short URL and starting after the given one.
+	ListURLs(ctx context.Context, after string, limit int) ([]URLRecord, error)
+	// DeleteURLs flags the short URLs of each user in owned, keyed by user ID,
+	// as deleted in one call. URLs a user does not own are skipped. Deleted
+	// URLs no longer count as duplicates, so they can be shortened again.
+	DeleteURLs(ctx context.Context, owned map[string][]string) error
+	// RecordClicks stores the events; either all of them or none are stored.
+	RecordClicks(ctx context.Context, events []ClickEvent) error
+	// ListClicks returns the click events of short URLs after from and up to
+	// and including to, in byte order of short URL and then by time.
+	ListClicks(ctx context.Context, from, to string) ([]ClickEvent, error)
+	GetURLStats(ctx context.Context, shortURL string) (URLStats, error)
+	PurgeExpired(ctx context.Context) (int, error)
+	CountURLs(ctx context.Context) (int, error)
+	// ReserveCounter reserves n short-code counter values and returns the
+	// first. Reserved values are never returned again, even after a restart.
+	ReserveCounter(ctx context.Context, n uint64) (uint64, error)
+	// AdvanceCounter raises the short-code counter to at least the given
+	// value and returns the counter; advancing to 0 only reads it.
+	AdvanceCounter(ctx context.Context, atLeast uint64) (uint64, error)
+	CountUsers(ctx context.Context) (int, error)
+	Ping(ctx context.Context) error
 	Close() error
 }
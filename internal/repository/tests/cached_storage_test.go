@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/repository/mocks"
+)
+
+func newCachedStorage(t *testing.T, size int) (*repository.CachedStorage, *mocks.MockStorage) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	inner := mocks.NewMockStorage(ctrl)
+	return repository.NewCachedStorage(inner, repository.CacheOptions{
+		Size:        size,
+		TTL:         time.Minute,
+		NegativeTTL: time.Minute,
+	}), inner
+}
+
+func TestCachedStorage_HitAndMiss(t *testing.T) {
+	cache, inner := newCachedStorage(t, 10)
+	ctx := context.Background()
+
+	inner.EXPECT().LookupURL(gomock.Any(), "short1").
+		Return(repository.URLRecord{ShortURL: "short1", OriginalURL: "https://example.com/"}, nil).
+		Times(1)
+	inner.EXPECT().LookupURL(gomock.Any(), "missing").Return(repository.URLRecord{}, repository.ErrNotFound).Times(1)
+
+	for i := 0; i < 3; i++ {
+		url, err := cache.GetOriginalURL(ctx, "short1")
+		if err != nil || url != "https://example.com/" {
+			t.Fatalf("Expected cached URL, got %q, %v", url, err)
+		}
+		if _, err := cache.GetOriginalURL(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 4 || stats.Misses != 2 || stats.Size != 2 {
+		t.Errorf("Expected 4 hits, 2 misses and 2 entries, got %+v", stats)
+	}
+}
+
+func TestCachedStorage_ClickLimitedPassesThrough(t *testing.T) {
+	cache, inner := newCachedStorage(t, 10)
+	ctx := context.Background()
+
+	record := repository.URLRecord{
+		ShortURL:    "limited",
+		OriginalURL: "https://example.com/",
+		URLLimits:   repository.URLLimits{MaxClicks: 5},
+	}
+	inner.EXPECT().LookupURL(gomock.Any(), "limited").Return(record, nil).Times(2)
+	inner.EXPECT().GetOriginalURL(gomock.Any(), "limited").Return("https://example.com/", nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetOriginalURL(ctx, "limited"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func TestCachedStorage_Invalidation(t *testing.T) {
+	cache, inner := newCachedStorage(t, 10)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		inner.EXPECT().LookupURL(gomock.Any(), "short1").
+			Return(repository.URLRecord{ShortURL: "short1", OriginalURL: "https://example.com/"}, nil),
+		inner.EXPECT().DeleteURLs(gomock.Any(), map[string][]string{"user-1": {"short1"}}).Return(nil),
+		inner.EXPECT().LookupURL(gomock.Any(), "short1").Return(repository.URLRecord{}, repository.ErrDeleted),
+	)
+
+	if _, err := cache.GetOriginalURL(ctx, "short1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := cache.DeleteURLs(ctx, map[string][]string{"user-1": {"short1"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := cache.GetOriginalURL(ctx, "short1"); !errors.Is(err, repository.ErrDeleted) {
+		t.Errorf("Expected ErrDeleted after delete, got %v", err)
+	}
+
+	// A cached miss is forgotten once the short code is created.
+	inner.EXPECT().LookupURL(gomock.Any(), "alias").Return(repository.URLRecord{}, repository.ErrNotFound)
+	inner.EXPECT().CreateShortURL(gomock.Any(), "uuid", "alias", "https://example.org/", "", gomock.Any()).
+		Return("alias", nil)
+	inner.EXPECT().LookupURL(gomock.Any(), "alias").
+		Return(repository.URLRecord{ShortURL: "alias", OriginalURL: "https://example.org/"}, nil)
+
+	_, _ = cache.GetOriginalURL(ctx, "alias")
+	if _, err := cache.CreateShortURL(ctx, "uuid", "alias", "https://example.org/", "", repository.URLLimits{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url, err := cache.GetOriginalURL(ctx, "alias"); err != nil || url != "https://example.org/" {
+		t.Errorf("Expected the new URL, got %q, %v", url, err)
+	}
+}
+
+func TestCachedStorage_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, inner := newCachedStorage(t, 2)
+	ctx := context.Background()
+
+	for _, short := range []string{"a", "b", "c"} {
+		inner.EXPECT().LookupURL(gomock.Any(), short).
+			Return(repository.URLRecord{ShortURL: short, OriginalURL: "https://example.com/" + short}, nil)
+	}
+	// "a" is evicted by "c" after "b" was used more recently.
+	inner.EXPECT().LookupURL(gomock.Any(), "a").
+		Return(repository.URLRecord{ShortURL: "a", OriginalURL: "https://example.com/a"}, nil)
+
+	for _, short := range []string{"a", "b", "b", "c", "b", "a"} {
+		if _, err := cache.GetOriginalURL(ctx, short); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if stats := cache.Stats(); stats.Size != 2 || stats.Hits != 2 {
+		t.Errorf("Expected 2 entries and 2 hits, got %+v", stats)
+	}
+}
+
+func TestCachedStorage_RespectsExpiry(t *testing.T) {
+	cache, inner := newCachedStorage(t, 10)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	record := repository.URLRecord{
+		ShortURL:    "soon",
+		OriginalURL: "https://example.com/",
+		URLLimits:   repository.URLLimits{ExpiresAt: &expiresAt},
+	}
+	inner.EXPECT().LookupURL(gomock.Any(), "soon").Return(record, nil)
+	inner.EXPECT().LookupURL(gomock.Any(), "soon").Return(repository.URLRecord{}, repository.ErrExpired)
+
+	if _, err := cache.GetOriginalURL(ctx, "soon"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetOriginalURL(ctx, "soon"); !errors.Is(err, repository.ErrExpired) {
+		t.Errorf("Expected ErrExpired once the URL expired, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestFileStorage_ReplayAndCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.jsonl")
+	opts := repository.FileStorageOptions{Sync: repository.SyncAlways}
+
+	storage, err := repository.NewFileStorage(path, opts)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	_, err = storage.CreateBatchURLs(ctx, []repository.BatchURLRequest{
+		{UUID: "1", ShortURL: "a", OriginalURL: "https://a.example", UserID: "user-1"},
+		{UUID: "2", ShortURL: "b", OriginalURL: "https://b.example", UserID: "user-1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create URLs: %v", err)
+	}
+	if err := storage.DeleteURLs(ctx, map[string][]string{"user-1": {"b"}}); err != nil {
+		t.Fatalf("Failed to delete URL: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	// Simulate a crash in the middle of an append.
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	_, _ = file.WriteString(`{"uuid":"3","short_url":"c","orig`)
+	file.Close()
+
+	storage, err = repository.NewFileStorage(path, opts)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer storage.Close()
+
+	if original, err := storage.GetOriginalURL(ctx, "a"); err != nil || original != "https://a.example" {
+		t.Errorf("Expected a to resolve, got %q, %v", original, err)
+	}
+	if _, err := storage.GetOriginalURL(ctx, "b"); err != repository.ErrDeleted {
+		t.Errorf("Expected b to stay deleted, got %v", err)
+	}
+	if _, err := storage.GetOriginalURL(ctx, "c"); err == nil {
+		t.Error("Expected the truncated record to be dropped")
+	}
+
+	if lines := countLines(t, path); lines != 3 {
+		t.Fatalf("Expected 3 log lines before compaction, got %d", lines)
+	}
+	if err := storage.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if lines := countLines(t, path); lines != 2 {
+		t.Errorf("Expected 2 log lines after compaction, got %d", lines)
+	}
+
+	if _, err := storage.CreateBatchURLs(ctx, []repository.BatchURLRequest{
+		{UUID: "4", ShortURL: "d", OriginalURL: "https://d.example"},
+	}); err != nil {
+		t.Fatalf("Failed to append after compaction: %v", err)
+	}
+	if lines := countLines(t, path); lines != 3 {
+		t.Errorf("Expected 3 log lines after append, got %d", lines)
+	}
+}
+
+func TestFileStorage_ConvertsLegacyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.json")
+	if err := os.WriteFile(path, []byte(`{"abc":"https://example.com"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	storage, err := repository.NewFileStorage(path, repository.FileStorageOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer storage.Close()
+
+	if original, err := storage.GetOriginalURL(context.Background(), "abc"); err != nil || original != "https://example.com" {
+		t.Errorf("Expected legacy record to resolve, got %q, %v", original, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.Contains(data, []byte(`"short_url":"abc"`)) {
+		t.Errorf("Expected file to be converted to JSON lines, got %s", data)
+	}
+}
+
+func TestFileStorage_ReserveCounter(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.jsonl")
+	opts := repository.FileStorageOptions{Sync: repository.SyncAlways}
+
+	storage, err := repository.NewFileStorage(path, opts)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if first, err := storage.ReserveCounter(ctx, 10); err != nil || first != 0 {
+		t.Fatalf("Expected the first reservation to start at 0, got %d, %v", first, err)
+	}
+	if _, err := storage.ReserveCounter(ctx, 10); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := storage.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	storage, err = repository.NewFileStorage(path, opts)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer storage.Close()
+
+	// Compaction keeps only the latest counter line.
+	if first, err := storage.ReserveCounter(ctx, 10); err != nil || first != 20 {
+		t.Errorf("Expected the reservation to resume at 20, got %d, %v", first, err)
+	}
+}
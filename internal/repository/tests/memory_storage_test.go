@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+func TestInMemoryStorage_CreateShortURL_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+
+	if _, err := storage.CreateShortURL(ctx, "1", "abc", "https://example.com", "", repository.URLLimits{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	short, err := storage.CreateShortURL(ctx, "2", "xyz", "https://example.com", "", repository.URLLimits{})
+	if !errors.Is(err, repository.ErrDuplicateURL) || short != "abc" {
+		t.Errorf("Expected duplicate of abc, got %q, %v", short, err)
+	}
+	if existing, _ := storage.GetShortURLByOriginal("https://example.com"); existing != "abc" {
+		t.Errorf("Expected index to point at abc, got %q", existing)
+	}
+}
+
+func TestInMemoryStorage_CreateBatchURLs_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+	if _, err := storage.CreateShortURL(ctx, "1", "abc", "https://example.com", "", repository.URLLimits{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		batch   []repository.BatchURLRequest
+		wantErr error
+	}{
+		{"stored original", []repository.BatchURLRequest{
+			{ShortURL: "new1", OriginalURL: "https://other.example"},
+			{ShortURL: "new2", OriginalURL: "https://example.com"},
+		}, repository.ErrDuplicateURL},
+		{"original repeated in batch", []repository.BatchURLRequest{
+			{ShortURL: "new1", OriginalURL: "https://other.example"},
+			{ShortURL: "new2", OriginalURL: "https://other.example"},
+		}, repository.ErrDuplicateURL},
+		{"short repeated in batch", []repository.BatchURLRequest{
+			{ShortURL: "new1", OriginalURL: "https://one.example"},
+			{ShortURL: "new1", OriginalURL: "https://two.example"},
+		}, repository.ErrShortURLTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := storage.CreateBatchURLs(ctx, tt.batch); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if _, err := storage.GetOriginalURL(ctx, "new1"); err == nil {
+				t.Error("Expected a rejected batch to store nothing")
+			}
+		})
+	}
+
+	// A deleted copy of a URL does not clash with its live one.
+	if _, err := storage.CreateBatchURLs(ctx, []repository.BatchURLRequest{
+		{ShortURL: "old", OriginalURL: "https://example.com", Deleted: true},
+		{ShortURL: "older", OriginalURL: "https://example.com", Deleted: true},
+	}); err != nil {
+		t.Errorf("Expected deleted records to be stored, got %v", err)
+	}
+}
+
+func TestInMemoryStorage_ConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			original := fmt.Sprintf("https://example.com/%d", i%10)
+			_, _ = storage.CreateShortURL(ctx, "", fmt.Sprintf("s%d", i), original, "", repository.URLLimits{})
+		}(i)
+	}
+	wg.Wait()
+
+	if count, _ := storage.CountURLs(ctx); count != 10 {
+		t.Errorf("Expected 10 distinct URLs, got %d", count)
+	}
+}
+
+func TestInMemoryStorage_ListURLs(t *testing.T) {
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+	for _, short := range []string{"ccc", "aaa", "ddd", "bbb"} {
+		if _, err := storage.CreateShortURL(ctx, short, short, "https://example.com/"+short, "user",
+			repository.URLLimits{}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	_ = storage.DeleteURLs(ctx, map[string][]string{"user": {"bbb"}})
+
+	var listed []string
+	after := ""
+	for {
+		page, err := storage.ListURLs(ctx, after, 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, record := range page {
+			listed = append(listed, record.ShortURL)
+		}
+		if len(page) < 3 {
+			break
+		}
+		after = page[len(page)-1].ShortURL
+	}
+
+	if fmt.Sprint(listed) != "[aaa bbb ccc ddd]" {
+		t.Errorf("Expected every record in order, deleted included, got %v", listed)
+	}
+}
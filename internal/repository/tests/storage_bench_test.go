@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+var benchSizes = []int{1_000, 100_000, 1_000_000}
+
+// prefill stores n URLs in batches so large sizes are quick to set up.
+func prefill(b *testing.B, storage repository.Storage, n int) {
+	b.Helper()
+	const batchSize = 10_000
+	batch := make([]repository.BatchURLRequest, 0, batchSize)
+	for i := 0; i < n; i++ {
+		batch = append(batch, repository.BatchURLRequest{
+			ShortURL:    fmt.Sprintf("p%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/prefill/%d", i),
+		})
+		if len(batch) == batchSize || i == n-1 {
+			if _, err := storage.CreateBatchURLs(context.Background(), batch); err != nil {
+				b.Fatalf("Failed to prefill storage: %v", err)
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+func benchmarkCreate(b *testing.B, open func(b *testing.B) repository.Storage) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			storage := open(b)
+			defer storage.Close()
+			prefill(b, storage, size)
+
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := storage.CreateShortURL(ctx, "", fmt.Sprintf("n%d", i),
+					fmt.Sprintf("https://example.com/new/%d", i), "", repository.URLLimits{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInMemoryStorage_CreateShortURL(b *testing.B) {
+	benchmarkCreate(b, func(*testing.B) repository.Storage {
+		return repository.NewInMemoryStorage()
+	})
+}
+
+func BenchmarkFileStorage_CreateShortURL(b *testing.B) {
+	benchmarkCreate(b, func(b *testing.B) repository.Storage {
+		storage, err := repository.NewFileStorage(
+			filepath.Join(b.TempDir(), "urls.jsonl"),
+			repository.FileStorageOptions{Sync: repository.SyncNever},
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return storage
+	})
+}
+
+func BenchmarkInMemoryStorage_DuplicateLookup(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			storage := repository.NewInMemoryStorage()
+			prefill(b, storage, size)
+
+			ctx := context.Background()
+			original := fmt.Sprintf("https://example.com/prefill/%d", size/2)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storage.CreateShortURL(ctx, "", "dup", original, "", repository.URLLimits{}); err == nil {
+					b.Fatal("Expected a duplicate")
+				}
+			}
+		})
+	}
+}
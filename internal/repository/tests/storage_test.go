@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+// backend opens a fresh storage; reopen returns it as a restart would, or
+// the same storage when nothing outlives the process.
+type backend struct {
+	name string
+	open func(t *testing.T) (storage repository.Storage, reopen func() repository.Storage)
+}
+
+var backends = []backend{
+	{"memory", func(_ *testing.T) (repository.Storage, func() repository.Storage) {
+		storage := repository.NewInMemoryStorage()
+		return storage, func() repository.Storage { return storage }
+	}},
+	{"file", func(t *testing.T) (repository.Storage, func() repository.Storage) {
+		path := filepath.Join(t.TempDir(), "urls.jsonl")
+		var storage repository.Storage
+		open := func() repository.Storage {
+			if storage != nil {
+				_ = storage.Close()
+			}
+			fileStorage, err := repository.NewFileStorage(path, repository.FileStorageOptions{})
+			if err != nil {
+				t.Fatalf("Failed to open file storage: %v", err)
+			}
+			storage = fileStorage
+			return storage
+		}
+		t.Cleanup(func() { _ = storage.Close() })
+		return open(), open
+	}},
+}
+
+// forEachBackend runs fn against every storage that keeps its records in
+// process memory.
+func forEachBackend(t *testing.T, fn func(t *testing.T, storage repository.Storage, reopen func() repository.Storage)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			storage, reopen := b.open(t)
+			fn(t, storage, reopen)
+		})
+	}
+}
+
+func TestCountUsers_IgnoresDeletedURLs(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, reopen func() repository.Storage) {
+		_, err := storage.CreateBatchURLs(ctx, []repository.BatchURLRequest{
+			{UUID: "1", ShortURL: "a", OriginalURL: "https://a.example", UserID: "alice"},
+			{UUID: "2", ShortURL: "b", OriginalURL: "https://b.example", UserID: "bob"},
+			{UUID: "3", ShortURL: "c", OriginalURL: "https://c.example", UserID: "bob"},
+			{UUID: "4", ShortURL: "d", OriginalURL: "https://d.example", UserID: "carol", Deleted: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create URLs: %v", err)
+		}
+		_ = storage.DeleteURLs(ctx, map[string][]string{"alice": {"a"}})
+		_ = storage.DeleteURLs(ctx, map[string][]string{"bob": {"b"}})
+		// Deleting again must not uncount bob's remaining URL.
+		_ = storage.DeleteURLs(ctx, map[string][]string{"bob": {"b"}})
+
+		for _, storage := range []repository.Storage{storage, reopen()} {
+			if urls, _ := storage.CountURLs(ctx); urls != 1 {
+				t.Errorf("Expected 1 URL, got %d", urls)
+			}
+			if users, _ := storage.CountUsers(ctx); users != 1 {
+				t.Errorf("Expected only bob to be counted, got %d users", users)
+			}
+		}
+	})
+}
+
+func createLimited(t *testing.T, storage repository.Storage, short string, limits repository.URLLimits) {
+	t.Helper()
+	if _, err := storage.CreateShortURL(context.Background(), "uuid-"+short, short, "https://example.com/"+short,
+		"owner", limits); err != nil {
+		t.Fatalf("Failed to create %s: %v", short, err)
+	}
+}
+
+func TestStorage_ClickLimit(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, reopen func() repository.Storage) {
+		createLimited(t, storage, "limited", repository.URLLimits{MaxClicks: 3})
+
+		if _, err := storage.GetOriginalURL(ctx, "limited"); err != nil {
+			t.Fatalf("Expected the first visit to resolve, got %v", err)
+		}
+		// The visit already counted must survive a restart.
+		storage = reopen()
+		for i := 0; i < 2; i++ {
+			if _, err := storage.GetOriginalURL(ctx, "limited"); err != nil {
+				t.Fatalf("Expected visit %d to resolve, got %v", i+2, err)
+			}
+		}
+		if _, err := storage.GetOriginalURL(ctx, "limited"); !errors.Is(err, repository.ErrExpired) {
+			t.Errorf("Expected the fourth visit to be refused, got %v", err)
+		}
+		if _, err := storage.LookupURL(ctx, "limited"); !errors.Is(err, repository.ErrExpired) {
+			t.Errorf("Expected the exhausted URL to look expired, got %v", err)
+		}
+
+		if _, err := reopen().GetOriginalURL(ctx, "limited"); !errors.Is(err, repository.ErrExpired) {
+			t.Errorf("Expected the limit to hold after a restart, got %v", err)
+		}
+	})
+}
+
+func TestStorage_TTL(t *testing.T) {
+	ctx := context.Background()
+	past, future := time.Now().Add(-time.Second), time.Now().Add(time.Hour)
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, reopen func() repository.Storage) {
+		createLimited(t, storage, "stale", repository.URLLimits{ExpiresAt: &past})
+		createLimited(t, storage, "fresh", repository.URLLimits{ExpiresAt: &future})
+
+		for _, storage := range []repository.Storage{storage, reopen()} {
+			if _, err := storage.GetOriginalURL(ctx, "stale"); !errors.Is(err, repository.ErrExpired) {
+				t.Errorf("Expected the expired URL to be refused, got %v", err)
+			}
+			if _, err := storage.GetOriginalURL(ctx, "fresh"); err != nil {
+				t.Errorf("Expected the URL to resolve before it expires, got %v", err)
+			}
+		}
+	})
+}
+
+func TestStorage_PurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Second)
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, reopen func() repository.Storage) {
+		createLimited(t, storage, "stale", repository.URLLimits{ExpiresAt: &past})
+		createLimited(t, storage, "used", repository.URLLimits{MaxClicks: 1})
+		createLimited(t, storage, "live", repository.URLLimits{MaxClicks: 5})
+		if _, err := storage.GetOriginalURL(ctx, "used"); err != nil {
+			t.Fatalf("Expected the visit to resolve, got %v", err)
+		}
+		if err := storage.RecordClicks(ctx, []repository.ClickEvent{
+			{ShortURL: "used", Timestamp: time.Now()},
+			{ShortURL: "live", Timestamp: time.Now()},
+		}); err != nil {
+			t.Fatalf("Failed to record clicks: %v", err)
+		}
+
+		if purged, err := storage.PurgeExpired(ctx); err != nil || purged != 2 {
+			t.Fatalf("Expected 2 URLs purged, got %d, %v", purged, err)
+		}
+		if purged, err := storage.PurgeExpired(ctx); err != nil || purged != 0 {
+			t.Errorf("Expected nothing left to purge, got %d, %v", purged, err)
+		}
+
+		for _, storage := range []repository.Storage{storage, reopen()} {
+			for _, short := range []string{"stale", "used"} {
+				if _, err := storage.LookupURL(ctx, short); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("Expected %s to be purged, got %v", short, err)
+				}
+			}
+			if clicks, _ := storage.ListClicks(ctx, "", "zzz"); len(clicks) != 1 || clicks[0].ShortURL != "live" {
+				t.Errorf("Expected only the live URL's clicks to remain, got %+v", clicks)
+			}
+			if count, _ := storage.CountURLs(ctx); count != 1 {
+				t.Errorf("Expected 1 URL left, got %d", count)
+			}
+		}
+	})
+}
+
+func TestStorage_ListURLsBetweenWrites(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Second)
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, _ func() repository.Storage) {
+		for _, short := range []string{"b", "d", "f"} {
+			createLimited(t, storage, short, repository.URLLimits{})
+		}
+		list := func() string {
+			t.Helper()
+			var codes string
+			for after := ""; ; {
+				page, err := storage.ListURLs(ctx, after, 2)
+				if err != nil {
+					t.Fatalf("Failed to list URLs: %v", err)
+				}
+				for _, record := range page {
+					codes += record.ShortURL
+				}
+				if len(page) < 2 {
+					return codes
+				}
+				after = page[len(page)-1].ShortURL
+			}
+		}
+		if got := list(); got != "bdf" {
+			t.Fatalf("Expected bdf, got %s", got)
+		}
+
+		createLimited(t, storage, "g", repository.URLLimits{})
+		createLimited(t, storage, "a", repository.URLLimits{})
+		createLimited(t, storage, "c", repository.URLLimits{ExpiresAt: &past})
+		if got := list(); got != "abcdfg" {
+			t.Errorf("Expected inserts to be listed in order, got %s", got)
+		}
+
+		if _, err := storage.PurgeExpired(ctx); err != nil {
+			t.Fatalf("Failed to purge: %v", err)
+		}
+		if got := list(); got != "abdfg" {
+			t.Errorf("Expected the purged code to be gone, got %s", got)
+		}
+	})
+}
+
+func TestStorage_ShortenDeletedURLAgain(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, storage repository.Storage, reopen func() repository.Storage) {
+		const originalURL = "https://example.com/again"
+		if _, err := storage.CreateShortURL(ctx, "1", "first", originalURL, "owner", repository.URLLimits{}); err != nil {
+			t.Fatalf("Failed to create URL: %v", err)
+		}
+		if err := storage.DeleteURLs(ctx, map[string][]string{"owner": {"first"}}); err != nil {
+			t.Fatalf("Failed to delete URL: %v", err)
+		}
+
+		if _, err := storage.CreateShortURL(ctx, "2", "second", originalURL, "owner", repository.URLLimits{}); err != nil {
+			t.Fatalf("Expected the deleted URL to be shortened again, got %v", err)
+		}
+
+		for _, storage := range []repository.Storage{storage, reopen()} {
+			_, err := storage.CreateShortURL(ctx, "3", "third", originalURL, "owner", repository.URLLimits{})
+			var duplicate *repository.DuplicateURLError
+			if !errors.As(err, &duplicate) || duplicate.ShortURL != "second" {
+				t.Errorf("Expected the live code to be reported as the duplicate, got %v", err)
+			}
+			if _, err := storage.LookupURL(ctx, "first"); !errors.Is(err, repository.ErrDeleted) {
+				t.Errorf("Expected the first code to stay deleted, got %v", err)
+			}
+		}
+	})
+}
@@ -1,12 +1,64 @@
 package repository
 
+import "time"
+
 type BatchURLRequest struct {
 	UUID        string
 	ShortURL    string
 	OriginalURL string
+	UserID      string
+	URLLimits
+	// Clicks and Deleted carry the state of a record copied from another
+	// storage; new URLs leave them zero.
+	Clicks  int
+	Deleted bool
 }
 
 type BatchURLOutput struct {
 	CorrelationID string
 	ShortURL      string
 }
+
+type URLRecord struct {
+	UUID        string `json:"uuid"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	DeletedFlag bool   `json:"is_deleted,omitempty"`
+	Clicks      int    `json:"clicks,omitempty"`
+	URLLimits
+}
+
+// URLLimits bound the lifetime of a short URL. Zero values mean no limit.
+type URLLimits struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks int        `json:"max_clicks,omitempty"`
+}
+
+// Expired reports whether a URL with these limits and the given click count
+// can no longer be resolved at the given time.
+func (l URLLimits) Expired(clicks int, now time.Time) bool {
+	if l.ExpiresAt != nil && !now.Before(*l.ExpiresAt) {
+		return true
+	}
+	return l.MaxClicks > 0 && clicks >= l.MaxClicks
+}
+
+type ClickEvent struct {
+	ShortURL  string    `json:"short_url"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPHash    string    `json:"ip_hash,omitempty"`
+}
+
+type URLStats struct {
+	TotalClicks    int
+	UniqueVisitors int
+	Daily          []DailyClicks
+}
+
+type DailyClicks struct {
+	Date   time.Time
+	Clicks int
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	minAliasLength = 3
+	maxAliasLength = 32
+	// maxShortCodeLength is the size of the short_url column.
+	maxShortCodeLength = 255
+)
+
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// reservedAliases collide with the service's own routes.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"ping":    {},
+	"metrics": {},
+	"static":  {},
+	"admin":   {},
+}
+
+// ValidateAlias checks that a caller-chosen short code is safe to use as a
+// path segment and does not shadow a service route.
+func ValidateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: must be %d to %d characters long", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+	return validateCode(alias)
+}
+
+// ValidateShortCode checks a short code issued elsewhere, such as one being
+// imported. Any length the storage holds is accepted, so generated codes and
+// UUIDs from batch requests pass as well as aliases.
+func ValidateShortCode(code string) error {
+	if code == "" || len(code) > maxShortCodeLength {
+		return fmt.Errorf("%w: must be 1 to %d characters long", ErrInvalidAlias, maxShortCodeLength)
+	}
+	return validateCode(code)
+}
+
+func validateCode(alias string) error {
+	for _, r := range alias {
+		if !isAliasRune(r) {
+			return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed", ErrInvalidAlias)
+		}
+	}
+
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+func isAliasRune(r rune) bool {
+	return r >= 'a' && r <= 'z' ||
+		r >= 'A' && r <= 'Z' ||
+		r >= '0' && r <= '9' ||
+		r == '-' || r == '_'
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	clickQueueSize     = 4096
+	clickBatchSize     = 500
+	clickFlushInterval = 2 * time.Second
+)
+
+// clickRecorder buffers click events off the redirect path and writes them to
+// storage in batches. Events are dropped rather than blocking the caller when
+// the buffer is full.
+type clickRecorder struct {
+	storage  repository.Storage
+	logger   *zap.Logger
+	events   chan repository.ClickEvent
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	dropped  atomic.Int64
+}
+
+func newClickRecorder(storage repository.Storage, logger *zap.Logger) *clickRecorder {
+	r := &clickRecorder{
+		storage: storage,
+		logger:  logger,
+		events:  make(chan repository.ClickEvent, clickQueueSize),
+		stop:    make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.run()
+
+	return r
+}
+
+func (r *clickRecorder) record(event repository.ClickEvent) {
+	select {
+	case <-r.stop:
+		return
+	default:
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// close stops accepting events and waits until buffered ones are written.
+func (r *clickRecorder) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+}
+
+func (r *clickRecorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]repository.ClickEvent, 0, clickBatchSize)
+
+	flush := func() {
+		if dropped := r.dropped.Swap(0); dropped > 0 {
+			r.logger.Warn("click events dropped, recorder buffer is full", zap.Int64("dropped", dropped))
+		}
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+		err := r.storage.RecordClicks(ctx, batch)
+		cancel()
+		if err != nil {
+			r.logger.Error("failed to record clicks", zap.Int("count", len(batch)), zap.Error(err))
+		}
+		batch = make([]repository.ClickEvent, 0, clickBatchSize)
+	}
+
+	for {
+		select {
+		case event := <-r.events:
+			batch = append(batch, event)
+			if len(batch) >= clickBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.stop:
+			for {
+				select {
+				case event := <-r.events:
+					batch = append(batch, event)
+					if len(batch) >= clickBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	deleteQueueSize     = 1024
+	deleteBatchSize     = 100
+	deleteFlushInterval = time.Second
+)
+
+var ErrDeleterStopped = errors.New("URL deleter is stopped")
+
+type deleteRequest struct {
+	userID    string
+	shortURLs []string
+}
+
+// urlDeleter fans in delete requests from all handlers into a single worker
+// that flags URLs as deleted in batches, one storage call per batch.
+type urlDeleter struct {
+	storage  repository.Storage
+	logger   *zap.Logger
+	requests chan deleteRequest
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newURLDeleter(storage repository.Storage, logger *zap.Logger) *urlDeleter {
+	d := &urlDeleter{
+		storage:  storage,
+		logger:   logger,
+		requests: make(chan deleteRequest, deleteQueueSize),
+		stop:     make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.run()
+
+	return d
+}
+
+func (d *urlDeleter) enqueue(ctx context.Context, userID string, shortURLs []string) error {
+	select {
+	case <-d.stop:
+		return ErrDeleterStopped
+	default:
+	}
+
+	select {
+	case d.requests <- deleteRequest{userID: userID, shortURLs: shortURLs}:
+		return nil
+	case <-d.stop:
+		return ErrDeleterStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting requests and waits until queued ones are flushed.
+func (d *urlDeleter) close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.wg.Wait()
+}
+
+func (d *urlDeleter) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(deleteFlushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string][]string)
+	size := 0
+
+	add := func(req deleteRequest) {
+		pending[req.userID] = append(pending[req.userID], req.shortURLs...)
+		size += len(req.shortURLs)
+		if size >= deleteBatchSize {
+			d.flush(pending)
+			size = 0
+		}
+	}
+
+	for {
+		select {
+		case req := <-d.requests:
+			add(req)
+		case <-ticker.C:
+			d.flush(pending)
+			size = 0
+		case <-d.stop:
+			for {
+				select {
+				case req := <-d.requests:
+					add(req)
+				default:
+					d.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *urlDeleter) flush(pending map[string][]string) {
+	if len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+	err := d.storage.DeleteURLs(ctx, pending)
+	cancel()
+	if err != nil {
+		d.logger.Error("failed to delete URLs", zap.Any("shortURLs", pending), zap.Error(err))
+	}
+	clear(pending)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Janitor periodically purges expired and exhausted URLs from storage.
+type Janitor struct {
+	storage  repository.Storage
+	logger   *zap.Logger
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewJanitor(storage repository.Storage, logger *zap.Logger, interval time.Duration) *Janitor {
+	j := &Janitor{
+		storage:  storage,
+		logger:   logger,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	j.wg.Add(1)
+	go j.run()
+
+	return j
+}
+
+// Close stops the janitor and waits for an in-progress purge to finish.
+func (j *Janitor) Close() {
+	j.stopOnce.Do(func() { close(j.stop) })
+	j.wg.Wait()
+}
+
+func (j *Janitor) run() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+			purged, err := j.storage.PurgeExpired(ctx)
+			cancel()
+			if err != nil {
+				j.logger.Error("failed to purge expired URLs", zap.Error(err))
+				continue
+			}
+			if purged > 0 {
+				j.logger.Info("purged expired URLs", zap.Int("count", purged))
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
@@ -5,10 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/hairutdin/url-shortener/internal/models"
+	service "github.com/hairutdin/url-shortener/internal/service"
 )
 
 // MockIURLService is a mock of IURLService interface.
@@ -35,18 +37,32 @@ func (m *MockIURLService) EXPECT() *MockIURLServiceMockRecorder {
 }
 
 // CreateShortURL mocks base method.
-func (m *MockIURLService) CreateShortURL(shortURL, originalURL string) (string, error) {
+func (m *MockIURLService) CreateShortURL(ctx context.Context, shortURL, originalURL, userID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateShortURL", shortURL, originalURL)
+	ret := m.ctrl.Call(m, "CreateShortURL", ctx, shortURL, originalURL, userID)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateShortURL indicates an expected call of CreateShortURL.
-func (mr *MockIURLServiceMockRecorder) CreateShortURL(shortURL, originalURL interface{}) *gomock.Call {
+func (mr *MockIURLServiceMockRecorder) CreateShortURL(ctx, shortURL, originalURL, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShortURL", reflect.TypeOf((*MockIURLService)(nil).CreateShortURL), shortURL, originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShortURL", reflect.TypeOf((*MockIURLService)(nil).CreateShortURL), ctx, shortURL, originalURL, userID)
+}
+
+// DeleteURLs mocks base method.
+func (m *MockIURLService) DeleteURLs(ctx context.Context, userID string, shortURLs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteURLs", ctx, userID, shortURLs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteURLs indicates an expected call of DeleteURLs.
+func (mr *MockIURLServiceMockRecorder) DeleteURLs(ctx, userID, shortURLs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURLs", reflect.TypeOf((*MockIURLService)(nil).DeleteURLs), ctx, userID, shortURLs)
 }
 
 // GetBaseURL mocks base method.
@@ -63,61 +79,170 @@ func (mr *MockIURLServiceMockRecorder) GetBaseURL() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBaseURL", reflect.TypeOf((*MockIURLService)(nil).GetBaseURL))
 }
 
+// GetInternalStats mocks base method.
+func (m *MockIURLService) GetInternalStats(ctx context.Context) (models.InternalStatsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInternalStats", ctx)
+	ret0, _ := ret[0].(models.InternalStatsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInternalStats indicates an expected call of GetInternalStats.
+func (mr *MockIURLServiceMockRecorder) GetInternalStats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInternalStats", reflect.TypeOf((*MockIURLService)(nil).GetInternalStats), ctx)
+}
+
 // GetOriginalURL mocks base method.
-func (m *MockIURLService) GetOriginalURL(shortURL string) (string, error) {
+func (m *MockIURLService) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOriginalURL", shortURL)
+	ret := m.ctrl.Call(m, "GetOriginalURL", ctx, shortURL)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOriginalURL indicates an expected call of GetOriginalURL.
-func (mr *MockIURLServiceMockRecorder) GetOriginalURL(shortURL interface{}) *gomock.Call {
+func (mr *MockIURLServiceMockRecorder) GetOriginalURL(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockIURLService)(nil).GetOriginalURL), ctx, shortURL)
+}
+
+// GetURLStats mocks base method.
+func (m *MockIURLService) GetURLStats(ctx context.Context, shortURL string) (models.URLStatsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLStats", ctx, shortURL)
+	ret0, _ := ret[0].(models.URLStatsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLStats indicates an expected call of GetURLStats.
+func (mr *MockIURLServiceMockRecorder) GetURLStats(ctx, shortURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockIURLService)(nil).GetOriginalURL), shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLStats", reflect.TypeOf((*MockIURLService)(nil).GetURLStats), ctx, shortURL)
+}
+
+// GetUserURLs mocks base method.
+func (m *MockIURLService) GetUserURLs(ctx context.Context, userID string) ([]models.UserURLResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserURLs", ctx, userID)
+	ret0, _ := ret[0].([]models.UserURLResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserURLs indicates an expected call of GetUserURLs.
+func (mr *MockIURLServiceMockRecorder) GetUserURLs(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockIURLService)(nil).GetUserURLs), ctx, userID)
 }
 
 // Ping mocks base method.
-func (m *MockIURLService) Ping() error {
+func (m *MockIURLService) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockIURLServiceMockRecorder) Ping() *gomock.Call {
+func (mr *MockIURLServiceMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIURLService)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIURLService)(nil).Ping), ctx)
+}
+
+// RecordClick mocks base method.
+func (m *MockIURLService) RecordClick(ctx context.Context, shortURL, referrer, userAgent, clientIP string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordClick", ctx, shortURL, referrer, userAgent, clientIP)
+}
+
+// RecordClick indicates an expected call of RecordClick.
+func (mr *MockIURLServiceMockRecorder) RecordClick(ctx, shortURL, referrer, userAgent, clientIP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClick", reflect.TypeOf((*MockIURLService)(nil).RecordClick), ctx, shortURL, referrer, userAgent, clientIP)
 }
 
 // ShortenBatchURLs mocks base method.
-func (m *MockIURLService) ShortenBatchURLs(requests []models.BatchShortenRequest) ([]models.BatchShortenResponse, error) {
+func (m *MockIURLService) ShortenBatchURLs(ctx context.Context, requests []models.BatchShortenRequest, userID string) ([]models.BatchShortenResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShortenBatchURLs", requests)
+	ret := m.ctrl.Call(m, "ShortenBatchURLs", ctx, requests, userID)
 	ret0, _ := ret[0].([]models.BatchShortenResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ShortenBatchURLs indicates an expected call of ShortenBatchURLs.
-func (mr *MockIURLServiceMockRecorder) ShortenBatchURLs(requests interface{}) *gomock.Call {
+func (mr *MockIURLServiceMockRecorder) ShortenBatchURLs(ctx, requests, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenBatchURLs", reflect.TypeOf((*MockIURLService)(nil).ShortenBatchURLs), requests)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenBatchURLs", reflect.TypeOf((*MockIURLService)(nil).ShortenBatchURLs), ctx, requests, userID)
 }
 
 // ShortenURL mocks base method.
-func (m *MockIURLService) ShortenURL(originalURL string) (string, error) {
+func (m *MockIURLService) ShortenURL(ctx context.Context, originalURL, userID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShortenURL", originalURL)
+	ret := m.ctrl.Call(m, "ShortenURL", ctx, originalURL, userID)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ShortenURL indicates an expected call of ShortenURL.
-func (mr *MockIURLServiceMockRecorder) ShortenURL(originalURL interface{}) *gomock.Call {
+func (mr *MockIURLServiceMockRecorder) ShortenURL(ctx, originalURL, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenURL", reflect.TypeOf((*MockIURLService)(nil).ShortenURL), ctx, originalURL, userID)
+}
+
+// ShortenURLWithOptions mocks base method.
+func (m *MockIURLService) ShortenURLWithOptions(ctx context.Context, originalURL, userID string, opts service.ShortenOptions) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShortenURLWithOptions", ctx, originalURL, userID, opts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShortenURLWithOptions indicates an expected call of ShortenURLWithOptions.
+func (mr *MockIURLServiceMockRecorder) ShortenURLWithOptions(ctx, originalURL, userID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenURLWithOptions", reflect.TypeOf((*MockIURLService)(nil).ShortenURLWithOptions), ctx, originalURL, userID, opts)
+}
+
+// MockHostPolicy is a mock of HostPolicy interface.
+type MockHostPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockHostPolicyMockRecorder
+}
+
+// MockHostPolicyMockRecorder is the mock recorder for MockHostPolicy.
+type MockHostPolicyMockRecorder struct {
+	mock *MockHostPolicy
+}
+
+// NewMockHostPolicy creates a new mock instance.
+func NewMockHostPolicy(ctrl *gomock.Controller) *MockHostPolicy {
+	mock := &MockHostPolicy{ctrl: ctrl}
+	mock.recorder = &MockHostPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHostPolicy) EXPECT() *MockHostPolicyMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockHostPolicy) Check(host string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", host)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockHostPolicyMockRecorder) Check(host interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShortenURL", reflect.TypeOf((*MockIURLService)(nil).ShortenURL), originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockHostPolicy)(nil).Check), host)
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidURL = errors.New("invalid URL")
+
+// Reasons reported by InvalidURLError.
+const (
+	ReasonMalformed         = "malformed"
+	ReasonUnsupportedScheme = "unsupported_scheme"
+	ReasonMissingHost       = "missing_host"
+	ReasonInvalidHost       = "invalid_host"
+	ReasonInvalidPort       = "invalid_port"
+	ReasonPrivateHost       = "private_host"
+	ReasonTooLong           = "too_long"
+)
+
+const maxURLLength = 2048
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// InvalidURLError explains why a URL was rejected. Reason is one of the
+// Reason constants and is meant for clients; Detail is for humans.
+type InvalidURLError struct {
+	Reason string
+	Detail string
+}
+
+func (e *InvalidURLError) Error() string {
+	return "invalid URL: " + e.Detail
+}
+
+func (e *InvalidURLError) Is(target error) bool {
+	return target == ErrInvalidURL
+}
+
+func invalidURL(reason, format string, args ...any) error {
+	return &InvalidURLError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// URLNormalizer validates URLs and rewrites them into a canonical form so
+// equivalent spellings are stored once. The zero value accepts public http
+// and https URLs and leaves the query untouched.
+type URLNormalizer struct {
+	// AllowedSchemes defaults to http and https when empty.
+	AllowedSchemes []string
+	// AllowPrivate accepts loopback, private and link-local hosts. Only
+	// literal addresses and localhost names are checked; host names are not
+	// resolved.
+	AllowPrivate bool
+	// SortQuery orders query parameters by key.
+	SortQuery bool
+}
+
+// Normalize returns the canonical form of raw: scheme and host lowercased,
+// IDN hosts converted to punycode, default ports, empty paths and fragments
+// dropped.
+func (n URLNormalizer) Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", invalidURL(ReasonMalformed, "URL is empty")
+	}
+	if len(raw) > maxURLLength {
+		return "", invalidURL(ReasonTooLong, "URL is longer than %d characters", maxURLLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", invalidURL(ReasonMalformed, "cannot parse URL")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !n.schemeAllowed(u.Scheme) {
+		return "", invalidURL(ReasonUnsupportedScheme, "scheme %q is not allowed", u.Scheme)
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", invalidURL(ReasonMissingHost, "URL has no host")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	if !n.AllowPrivate && isPrivateHost(host) {
+		return "", invalidURL(ReasonPrivateHost, "host %q is not publicly routable", host)
+	}
+
+	port := u.Port()
+	if port != "" {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return "", invalidURL(ReasonInvalidPort, "port %q is out of range", port)
+		}
+	}
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if n.SortQuery && u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func (n URLNormalizer) schemeAllowed(scheme string) bool {
+	allowed := n.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = []string{"http", "https"}
+	}
+	for _, s := range allowed {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) (string, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.WithZone("").String(), nil
+	}
+	if addr, ok, err := parseNumericIPv4(host); ok || err != nil {
+		return addr.String(), err
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil || ascii == "" {
+		return "", invalidURL(ReasonInvalidHost, "host %q is not a valid domain name", host)
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// parseNumericIPv4 parses the IPv4 spellings inet_aton and browsers accept
+// besides dotted decimal, such as 2130706433, 127.1 and 0x7f.1: one to four
+// parts in decimal, octal with a leading 0 or hex with 0x, the last filling
+// the remaining bytes. ok is false if host is a domain name; a host whose last
+// label is a number but is not a valid address is an error.
+func parseNumericIPv4(host string) (addr netip.Addr, ok bool, err error) {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if !isIPv4Number(parts[len(parts)-1]) {
+		return netip.Addr{}, false, nil
+	}
+	invalid := invalidURL(ReasonInvalidHost, "host %q is not a valid IPv4 address", host)
+	if len(parts) > 4 {
+		return netip.Addr{}, false, invalid
+	}
+
+	var n uint64
+	for i, part := range parts {
+		value, err := parseIPv4Part(part)
+		if err != nil {
+			return netip.Addr{}, false, invalid
+		}
+		if i < len(parts)-1 {
+			if value > 255 {
+				return netip.Addr{}, false, invalid
+			}
+			n |= value << (8 * (3 - i))
+			continue
+		}
+		if value >= 1<<(8*(4-i)) {
+			return netip.Addr{}, false, invalid
+		}
+		n |= value
+	}
+	return netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}), true, nil
+}
+
+// isIPv4Number reports whether label is a decimal or 0x-prefixed hex number.
+func isIPv4Number(label string) bool {
+	digits := "0123456789"
+	if len(label) > 1 && (label[:2] == "0x" || label[:2] == "0X") {
+		label, digits = label[2:], "0123456789abcdefABCDEF"
+		if label == "" {
+			return true
+		}
+	}
+	return label != "" && strings.Trim(label, digits) == ""
+}
+
+func parseIPv4Part(part string) (uint64, error) {
+	base := 10
+	switch {
+	case len(part) > 1 && (part[:2] == "0x" || part[:2] == "0X"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, nil
+		}
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+	return strconv.ParseUint(part, base, 32)
+}
+
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified()
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+var ErrInvalidExpiry = errors.New("invalid expiration settings")
+
+// ShortenOptions are the optional settings of a new short URL. ExpiresAt
+// takes precedence over TTL when both are set.
+type ShortenOptions struct {
+	Alias     string
+	ExpiresAt *time.Time
+	TTL       time.Duration
+	MaxClicks int
+}
+
+func (o ShortenOptions) limits(now time.Time) (repository.URLLimits, error) {
+	if o.TTL < 0 || o.MaxClicks < 0 {
+		return repository.URLLimits{}, ErrInvalidExpiry
+	}
+
+	limits := repository.URLLimits{MaxClicks: o.MaxClicks}
+	switch {
+	case o.ExpiresAt != nil:
+		if !o.ExpiresAt.After(now) {
+			return repository.URLLimits{}, ErrInvalidExpiry
+		}
+		expiresAt := o.ExpiresAt.UTC()
+		limits.ExpiresAt = &expiresAt
+	case o.TTL > 0:
+		expiresAt := now.Add(o.TTL).UTC()
+		limits.ExpiresAt = &expiresAt
+	}
+	return limits, nil
+}
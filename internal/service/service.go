@@ -1,14 +1,32 @@
 package service
 
 import (
+	"context"
+
 	"github.com/hairutdin/url-shortener/internal/models"
 )
 
 type IURLService interface {
-	ShortenURL(originalURL string) (string, error)
-	CreateShortURL(shortURL, originalURL string) (string, error)
-	ShortenBatchURLs(requests []models.BatchShortenRequest) ([]models.BatchShortenResponse, error)
-	GetOriginalURL(shortURL string) (string, error)
-	Ping() error
+	ShortenURL(ctx context.Context, originalURL, userID string) (string, error)
+	ShortenURLWithOptions(ctx context.Context, originalURL, userID string, opts ShortenOptions) (string, error)
+	CreateShortURL(ctx context.Context, shortURL, originalURL, userID string) (string, error)
+	ShortenBatchURLs(
+		ctx context.Context,
+		requests []models.BatchShortenRequest,
+		userID string,
+	) ([]models.BatchShortenResponse, error)
+	GetOriginalURL(ctx context.Context, shortURL string) (string, error)
+	GetUserURLs(ctx context.Context, userID string) ([]models.UserURLResponse, error)
+	DeleteURLs(ctx context.Context, userID string, shortURLs []string) error
+	RecordClick(ctx context.Context, shortURL, referrer, userAgent, clientIP string)
+	GetURLStats(ctx context.Context, shortURL string) (models.URLStatsResponse, error)
+	GetInternalStats(ctx context.Context) (models.InternalStatsResponse, error)
+	Ping(ctx context.Context) error
 	GetBaseURL() string
 }
+
+// HostPolicy decides whether URLs pointing at a host may be shortened and
+// resolved. Check returns an error describing the rule that blocked host.
+type HostPolicy interface {
+	Check(host string) error
+}
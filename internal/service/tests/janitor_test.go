@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestJanitor_PurgesExpiredURLs(t *testing.T) {
+	ctx := context.Background()
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "urls.jsonl"), repository.FileStorageOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open file storage: %v", err)
+	}
+	defer fileStorage.Close()
+
+	for name, storage := range map[string]repository.Storage{
+		"memory": repository.NewInMemoryStorage(),
+		"file":   fileStorage,
+	} {
+		t.Run(name, func(t *testing.T) {
+			expiresAt := time.Now().Add(50 * time.Millisecond)
+			if _, err := storage.CreateShortURL(ctx, "1", "brief", "https://example.com/brief", "",
+				repository.URLLimits{ExpiresAt: &expiresAt}); err != nil {
+				t.Fatalf("Failed to create URL: %v", err)
+			}
+			if _, err := storage.CreateShortURL(ctx, "2", "lasting", "https://example.com/lasting", "",
+				repository.URLLimits{}); err != nil {
+				t.Fatalf("Failed to create URL: %v", err)
+			}
+
+			janitor := service.NewJanitor(storage, zap.NewNop(), 10*time.Millisecond)
+			defer janitor.Close()
+
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				_, err := storage.LookupURL(ctx, "brief")
+				if errors.Is(err, repository.ErrNotFound) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected the janitor to purge the expired URL, got %v", err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if _, err := storage.LookupURL(ctx, "lasting"); err != nil {
+				t.Errorf("Expected the URL without limits to stay, got %v", err)
+			}
+		})
+	}
+}
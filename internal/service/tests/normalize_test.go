@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hairutdin/url-shortener/internal/service"
+)
+
+func TestURLNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer service.URLNormalizer
+		input      string
+		want       string
+		wantReason string
+	}{
+		{name: "lowercases host", input: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "adds root path", input: "https://example.com", want: "https://example.com/"},
+		{name: "strips default port", input: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "keeps other port", input: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{name: "drops fragment", input: "https://example.com/a#top", want: "https://example.com/a"},
+		{name: "punycode host", input: "https://Bücher.example/", want: "https://xn--bcher-kva.example/"},
+		{name: "keeps query order", input: "https://example.com/?b=2&a=1", want: "https://example.com/?b=2&a=1"},
+		{
+			name:       "sorts query",
+			normalizer: service.URLNormalizer{SortQuery: true},
+			input:      "https://example.com/?b=2&a=1",
+			want:       "https://example.com/?a=1&b=2",
+		},
+		{name: "javascript scheme", input: "javascript:alert(1)", wantReason: service.ReasonUnsupportedScheme},
+		{name: "garbage", input: "not a url", wantReason: service.ReasonUnsupportedScheme},
+		{name: "empty", input: "  ", wantReason: service.ReasonMalformed},
+		{name: "no host", input: "https:///path", wantReason: service.ReasonMissingHost},
+		{name: "bad port", input: "https://example.com:99999/", wantReason: service.ReasonInvalidPort},
+		{name: "loopback", input: "http://127.0.0.1/admin", wantReason: service.ReasonPrivateHost},
+		{name: "decimal loopback", input: "http://2130706433/", wantReason: service.ReasonPrivateHost},
+		{name: "short loopback", input: "http://127.1/", wantReason: service.ReasonPrivateHost},
+		{name: "hex loopback", input: "http://0x7f.1/", wantReason: service.ReasonPrivateHost},
+		{name: "octal private", input: "http://012.0.0.1/", wantReason: service.ReasonPrivateHost},
+		{name: "numeric public", input: "http://0x5db8d822/", want: "http://93.184.216.34/"},
+		{name: "numeric out of range", input: "http://4294967296/", wantReason: service.ReasonInvalidHost},
+		{name: "numeric label in domain", input: "https://v2.example/", want: "https://v2.example/"},
+		{name: "private IPv6", input: "http://[fd00::1]/", wantReason: service.ReasonPrivateHost},
+		{name: "localhost", input: "http://LOCALHOST:8080/", wantReason: service.ReasonPrivateHost},
+		{
+			name:       "private allowed",
+			normalizer: service.URLNormalizer{AllowPrivate: true},
+			input:      "http://[::1]:8080",
+			want:       "http://[::1]:8080/",
+		},
+		{
+			name:       "custom schemes",
+			normalizer: service.URLNormalizer{AllowedSchemes: []string{"https"}},
+			input:      "http://example.com/",
+			wantReason: service.ReasonUnsupportedScheme,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.normalizer.Normalize(tt.input)
+			if tt.wantReason != "" {
+				var invalid *service.InvalidURLError
+				if !errors.As(err, &invalid) || invalid.Reason != tt.wantReason {
+					t.Fatalf("Expected reason %s, got %v", tt.wantReason, err)
+				}
+				if !errors.Is(err, service.ErrInvalidURL) {
+					t.Errorf("Expected error to match ErrInvalidURL")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
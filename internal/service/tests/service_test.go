@@ -1,34 +1,48 @@
 package tests
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/hairutdin/url-shortener/internal/lib"
 	"github.com/hairutdin/url-shortener/internal/models"
+	"github.com/hairutdin/url-shortener/internal/policy"
 	"github.com/hairutdin/url-shortener/internal/repository"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hairutdin/url-shortener/internal/repository/mocks"
 	"github.com/hairutdin/url-shortener/internal/service"
+	servicemocks "github.com/hairutdin/url-shortener/internal/service/mocks"
 	"go.uber.org/zap"
 )
 
+func randomCodes(t *testing.T) lib.CodeGenerator {
+	t.Helper()
+	codes, err := lib.NewRandomCodeGenerator(8)
+	if err != nil {
+		t.Fatalf("Failed to create code generator: %v", err)
+	}
+	return codes
+}
+
 func TestCreateShortURL_Duplicate(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockStorage := mocks.NewMockStorage(ctrl)
 	logger, _ := zap.NewDevelopment()
-	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080")
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
 
-	originalURL := "https://example.com"
+	originalURL := "https://example.com/"
 	shortURL := "short123"
 
 	mockStorage.EXPECT().
-		CreateShortURL(gomock.Any(), gomock.Any(), originalURL).
+		CreateShortURL(gomock.Any(), gomock.Any(), gomock.Any(), originalURL, gomock.Any(), gomock.Any()).
 		Return(shortURL, repository.ErrDuplicateURL)
 
-	result, err := urlService.CreateShortURL(shortURL, originalURL)
+	result, err := urlService.CreateShortURL(context.Background(), shortURL, originalURL, "")
 
 	if err != repository.ErrDuplicateURL {
 		t.Errorf("Expected ErrDuplicateURL, got %v", err)
@@ -45,19 +59,18 @@ func TestShortenBatchURLs_ErrorOnDuplicate(t *testing.T) {
 
 	mockStorage := mocks.NewMockStorage(ctrl)
 	logger, _ := zap.NewDevelopment()
-	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080")
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
 
 	requests := []models.BatchShortenRequest{
-		{CorrelationID: "1", OriginalURL: "https://example1.com"},
-		{CorrelationID: "2", OriginalURL: "https://example2.com"},
+		{CorrelationID: "1", OriginalURL: "https://example1.com/"},
+		{CorrelationID: "2", OriginalURL: "https://example2.com/"},
 	}
 
-	mockStorage.EXPECT().CreateShortURL(gomock.Any(), gomock.Any(), requests[0].OriginalURL).Return("short1", nil)
 	mockStorage.EXPECT().
-		CreateShortURL(gomock.Any(), gomock.Any(), requests[1].OriginalURL).
-		Return("", repository.ErrDuplicateURL)
+		CreateBatchURLs(gomock.Any(), gomock.Len(2)).
+		Return(nil, repository.ErrDuplicateURL)
 
-	batchResponse, err := urlService.ShortenBatchURLs(requests)
+	batchResponse, err := urlService.ShortenBatchURLs(context.Background(), requests, "")
 
 	if err != repository.ErrDuplicateURL {
 		t.Errorf("Expected ErrDuplicateURL, got %v", err)
@@ -68,25 +81,382 @@ func TestShortenBatchURLs_ErrorOnDuplicate(t *testing.T) {
 	}
 }
 
+func TestShortenBatchURLs_InvalidRowStoresNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No storage call is expected: the bad second row fails the batch first.
+	mockStorage := mocks.NewMockStorage(ctrl)
+	urlService := service.NewURLService(mockStorage, zap.NewNop(), "http://localhost:8080", randomCodes(t),
+		service.URLNormalizer{}, nil)
+
+	requests := []models.BatchShortenRequest{
+		{CorrelationID: "1", OriginalURL: "https://example1.com/"},
+		{CorrelationID: "2", OriginalURL: "not a url"},
+	}
+	if _, err := urlService.ShortenBatchURLs(context.Background(), requests, ""); err == nil {
+		t.Error("Expected the invalid row to fail the batch")
+	}
+}
+
+func TestShortenBatchURLs_RegeneratesTakenCodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	urlService := service.NewURLService(mockStorage, zap.NewNop(), "http://localhost:8080", randomCodes(t),
+		service.URLNormalizer{}, nil)
+
+	var stored []repository.BatchURLRequest
+	gomock.InOrder(
+		mockStorage.EXPECT().CreateBatchURLs(gomock.Any(), gomock.Any()).Return(nil, repository.ErrShortURLTaken),
+		mockStorage.EXPECT().CreateBatchURLs(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, rows []repository.BatchURLRequest) ([]repository.BatchURLOutput, error) {
+				stored = append(stored, rows...)
+				return nil, nil
+			}),
+	)
+
+	requests := []models.BatchShortenRequest{
+		{CorrelationID: "1", OriginalURL: "https://example1.com/", Alias: "my-alias"},
+		{CorrelationID: "2", OriginalURL: "https://example2.com/"},
+	}
+	batchResponse, err := urlService.ShortenBatchURLs(context.Background(), requests, "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(stored) != 2 || stored[0].ShortURL != "my-alias" || stored[1].ShortURL == "" {
+		t.Fatalf("Expected the alias kept and a code generated, got %+v", stored)
+	}
+	want := "http://localhost:8080/" + stored[1].ShortURL
+	if batchResponse[1].CorrelationID != "2" || batchResponse[1].ShortURL != want {
+		t.Errorf("Expected %q for correlation 2, got %+v", want, batchResponse[1])
+	}
+}
+
 func TestGetOriginalURL_NotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockStorage := mocks.NewMockStorage(ctrl)
 	logger, _ := zap.NewDevelopment()
-	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080")
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
 
 	shortURL := "short-not-exist"
 
-	mockStorage.EXPECT().GetOriginalURL(shortURL).Return("", errors.New("not found"))
+	mockStorage.EXPECT().GetOriginalURL(gomock.Any(), shortURL).Return("", repository.ErrNotFound)
 
-	result, err := urlService.GetOriginalURL(shortURL)
+	result, err := urlService.GetOriginalURL(context.Background(), shortURL)
 
-	if err == nil || err.Error() != "URL not found" {
-		t.Errorf("Expected 'URL not found' error, got %v", err)
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
 	}
 
 	if result != "" {
 		t.Errorf("Expected empty result, got %s", result)
 	}
 }
+
+func TestGetOriginalURL_StorageFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	outage := errors.New("connection refused")
+	mockStorage.EXPECT().GetOriginalURL(gomock.Any(), "short123").Return("", outage)
+
+	_, err := urlService.GetOriginalURL(context.Background(), "short123")
+
+	if !errors.Is(err, outage) || errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected the storage error to pass through, got %v", err)
+	}
+}
+
+func TestGetUserURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	userID := "user-1"
+
+	mockStorage.EXPECT().GetUserURLs(gomock.Any(), userID).Return([]repository.URLRecord{
+		{ShortURL: "short1", OriginalURL: "https://example1.com", UserID: userID},
+		{ShortURL: "short2", OriginalURL: "https://example2.com", UserID: userID},
+	}, nil)
+
+	result, err := urlService.GetUserURLs(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []models.UserURLResponse{
+		{ShortURL: "http://localhost:8080/short1", OriginalURL: "https://example1.com"},
+		{ShortURL: "http://localhost:8080/short2", OriginalURL: "https://example2.com"},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d URLs, got %d", len(expected), len(result))
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], result[i])
+		}
+	}
+}
+
+func TestDeleteURLs_FlushesOnClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	// Both users' URLs are flagged in a single storage call.
+	mockStorage.EXPECT().DeleteURLs(gomock.Any(), map[string][]string{
+		"user-1": {"short1", "short2", "short3"},
+		"user-2": {"short4"},
+	}).Return(nil)
+
+	if err := urlService.DeleteURLs(context.Background(), "user-1", []string{"short1", "short2"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := urlService.DeleteURLs(context.Background(), "user-2", []string{"short4"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := urlService.DeleteURLs(context.Background(), "user-1", []string{"short3"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	urlService.Close()
+
+	if err := urlService.DeleteURLs(context.Background(), "user-1", []string{"short5"}); err != service.ErrDeleterStopped {
+		t.Errorf("Expected ErrDeleterStopped after Close, got %v", err)
+	}
+}
+
+func TestRecordClick_FlushesOnClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	mockStorage.EXPECT().
+		RecordClicks(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, events []repository.ClickEvent) error {
+			if len(events) != 2 {
+				t.Errorf("Expected 2 click events, got %d", len(events))
+				return nil
+			}
+			if events[0].ShortURL != "short1" || events[0].Referrer != "https://ref.example" {
+				t.Errorf("Unexpected click event: %+v", events[0])
+			}
+			if events[0].IPHash == "" || events[0].IPHash == "10.0.0.1" {
+				t.Errorf("Expected client IP to be hashed, got %q", events[0].IPHash)
+			}
+			if events[0].IPHash != events[1].IPHash {
+				t.Errorf("Expected equal hashes for the same client IP")
+			}
+			return nil
+		})
+
+	urlService.RecordClick(context.Background(), "short1", "https://ref.example", "test-agent", "10.0.0.1")
+	urlService.RecordClick(context.Background(), "short1", "", "test-agent", "10.0.0.1")
+
+	urlService.Close()
+}
+
+func TestGetURLStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	mockStorage.EXPECT().GetURLStats(gomock.Any(), "short1").Return(repository.URLStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Daily: []repository.DailyClicks{
+			{Date: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), Clicks: 1},
+			{Date: time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), Clicks: 2},
+		},
+	}, nil)
+
+	stats, err := urlService.GetURLStats(context.Background(), "short1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stats.TotalClicks != 3 || stats.UniqueVisitors != 2 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+	if len(stats.Daily) != 2 || stats.Daily[0].Date != "2024-10-01" || stats.Daily[1].Clicks != 2 {
+		t.Errorf("Unexpected daily histogram: %+v", stats.Daily)
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias string
+		valid bool
+	}{
+		{alias: "my-link_1", valid: true},
+		{alias: "ab", valid: false},
+		{alias: "this-alias-is-definitely-longer-than-allowed", valid: false},
+		{alias: "bad/alias", valid: false},
+		{alias: "пример", valid: false},
+		{alias: "ping", valid: false},
+		{alias: "API", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := service.ValidateAlias(tt.alias)
+		if tt.valid && err != nil {
+			t.Errorf("Expected alias %q to be valid, got %v", tt.alias, err)
+		}
+		if !tt.valid && !errors.Is(err, service.ErrInvalidAlias) {
+			t.Errorf("Expected alias %q to be rejected, got %v", tt.alias, err)
+		}
+	}
+}
+
+func TestShortenURLWithOptions_AliasTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	mockStorage.EXPECT().
+		CreateShortURL(gomock.Any(), gomock.Any(), "my-link", "https://example.com/", "user-1", repository.URLLimits{}).
+		Return("", repository.ErrShortURLTaken)
+
+	_, err := urlService.ShortenURLWithOptions(
+		context.Background(), "https://example.com", "user-1", service.ShortenOptions{Alias: "my-link"})
+	if !errors.Is(err, repository.ErrShortURLTaken) {
+		t.Errorf("Expected ErrShortURLTaken, got %v", err)
+	}
+}
+
+func TestShortenURLWithOptions_Limits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	mockStorage.EXPECT().
+		CreateShortURL(gomock.Any(), gomock.Any(), gomock.Any(), "https://example.com/", "user-1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, shortURL, _, _ string, limits repository.URLLimits) (string, error) {
+			if limits.MaxClicks != 5 {
+				t.Errorf("Expected max clicks 5, got %d", limits.MaxClicks)
+			}
+			if limits.ExpiresAt == nil || time.Until(*limits.ExpiresAt) > time.Hour {
+				t.Errorf("Expected expiry within an hour, got %v", limits.ExpiresAt)
+			}
+			return shortURL, nil
+		})
+
+	ctx := context.Background()
+	_, err := urlService.ShortenURLWithOptions(ctx, "https://example.com", "user-1", service.ShortenOptions{
+		TTL:       time.Hour,
+		MaxClicks: 5,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	_, err = urlService.ShortenURLWithOptions(ctx, "https://example.com", "user-1", service.ShortenOptions{ExpiresAt: &past})
+	if !errors.Is(err, service.ErrInvalidExpiry) {
+		t.Errorf("Expected ErrInvalidExpiry for a past expiry, got %v", err)
+	}
+}
+
+func TestShortenURL_RetriesCollisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	codes, _ := lib.NewHashCodeGenerator(8)
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", codes, service.URLNormalizer{}, nil)
+
+	var tried []string
+	mockStorage.EXPECT().
+		CreateShortURL(gomock.Any(), gomock.Any(), gomock.Any(), "https://example.com/", "", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, shortURL, _, _ string, _ repository.URLLimits) (string, error) {
+			tried = append(tried, shortURL)
+			if len(tried) < 3 {
+				return "", repository.ErrShortURLTaken
+			}
+			return shortURL, nil
+		}).
+		Times(3)
+
+	shortURL, err := urlService.ShortenURL(context.Background(), "https://example.com", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if shortURL != tried[2] {
+		t.Errorf("Expected %s, got %s", tried[2], shortURL)
+	}
+	if tried[0] == tried[1] || tried[1] == tried[2] {
+		t.Errorf("Expected a new code on every attempt, got %v", tried)
+	}
+}
+
+func TestShortenURL_GivesUpAfterCollisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, nil)
+
+	mockStorage.EXPECT().
+		CreateShortURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", repository.ErrShortURLTaken).
+		Times(5)
+
+	_, err := urlService.ShortenURL(context.Background(), "https://example.com", "")
+	if !errors.Is(err, service.ErrNoFreeCode) {
+		t.Errorf("Expected ErrNoFreeCode, got %v", err)
+	}
+}
+
+func TestHostPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	mockPolicy := servicemocks.NewMockHostPolicy(ctrl)
+	logger, _ := zap.NewDevelopment()
+	urlService := service.NewURLService(
+		mockStorage, logger, "http://localhost:8080", randomCodes(t), service.URLNormalizer{}, mockPolicy)
+
+	blocked := &policy.BlockedError{Host: "phish.example", Rule: "deny phish.example"}
+	mockPolicy.EXPECT().Check("phish.example").Return(blocked).Times(2)
+
+	_, err := urlService.ShortenURL(context.Background(), "https://PHISH.example/login", "")
+	if !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected ErrBlocked when shortening, got %v", err)
+	}
+
+	// A link created before the host was blocked stops resolving.
+	mockStorage.EXPECT().GetOriginalURL(gomock.Any(), "short1").Return("https://phish.example/login", nil)
+	_, err = urlService.GetOriginalURL(context.Background(), "short1")
+	if !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected ErrBlocked when resolving, got %v", err)
+	}
+}
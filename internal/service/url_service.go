@@ -1,89 +1,369 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/hairutdin/url-shortener/internal/lib"
 	"github.com/hairutdin/url-shortener/internal/models"
 	"github.com/hairutdin/url-shortener/internal/repository"
 	"go.uber.org/zap"
 )
 
+// backgroundTimeout bounds storage calls made by the background workers,
+// which outlive the requests that queued the work.
+const backgroundTimeout = 30 * time.Second
+
+// maxCodeAttempts bounds how many generated short codes are tried before
+// ErrNoFreeCode is reported to the caller.
+const maxCodeAttempts = 5
+
+// ErrNoFreeCode is returned when every generated short code tried for a URL
+// was already taken. It is a server-side failure, unlike a taken alias.
+var ErrNoFreeCode = errors.New("no free short code found")
+
 type URLService struct {
 	storage repository.Storage
 	logger  *zap.Logger
 	baseURL string
+	codes   lib.CodeGenerator
+	urls    URLNormalizer
+	policy  HostPolicy
+	deleter *urlDeleter
+	clicks  *clickRecorder
 }
 
 var _ IURLService = (*URLService)(nil)
 
-func NewURLService(storage repository.Storage, logger *zap.Logger, baseURL string) *URLService {
+func NewURLService(
+	storage repository.Storage,
+	logger *zap.Logger,
+	baseURL string,
+	codes lib.CodeGenerator,
+	urls URLNormalizer,
+	policy HostPolicy,
+) *URLService {
 	return &URLService{
 		storage: storage,
 		logger:  logger,
 		baseURL: baseURL,
+		codes:   codes,
+		urls:    urls,
+		policy:  policy,
+		deleter: newURLDeleter(storage, logger),
+		clicks:  newClickRecorder(storage, logger),
 	}
 }
 
+// log returns the request-scoped logger carried by ctx, if any.
+func (s *URLService) log(ctx context.Context) *zap.Logger {
+	return lib.LoggerFromContext(ctx, s.logger)
+}
+
 func (s *URLService) GetBaseURL() string {
 	return s.baseURL
 }
 
-func (s *URLService) ShortenURL(originalURL string) (string, error) {
-	shortURL, err := lib.GenerateShortURL()
+func (s *URLService) ShortenURL(ctx context.Context, originalURL, userID string) (string, error) {
+	originalURL, err := s.prepareURL(ctx, originalURL)
+	if err != nil {
+		return "", err
+	}
+	return s.createGeneratedShortURL(ctx, originalURL, userID, repository.URLLimits{})
+}
+
+// ShortenURLWithOptions stores the URL under a caller-chosen alias or a
+// generated short code, with optional expiration limits.
+func (s *URLService) ShortenURLWithOptions(
+	ctx context.Context,
+	originalURL, userID string,
+	opts ShortenOptions,
+) (string, error) {
+	limits, err := opts.limits(time.Now())
 	if err != nil {
 		return "", err
 	}
+	if originalURL, err = s.prepareURL(ctx, originalURL); err != nil {
+		return "", err
+	}
 
-	return s.CreateShortURL(shortURL, originalURL)
+	if opts.Alias == "" {
+		return s.createGeneratedShortURL(ctx, originalURL, userID, limits)
+	}
+	if err := ValidateAlias(opts.Alias); err != nil {
+		return "", err
+	}
+	return s.createShortURL(ctx, opts.Alias, originalURL, userID, limits)
 }
 
-func (s *URLService) CreateShortURL(shortURL, originalURL string) (string, error) {
+func (s *URLService) CreateShortURL(ctx context.Context, shortURL, originalURL, userID string) (string, error) {
+	originalURL, err := s.prepareURL(ctx, originalURL)
+	if err != nil {
+		return "", err
+	}
+	return s.createShortURL(ctx, shortURL, originalURL, userID, repository.URLLimits{})
+}
+
+func (s *URLService) createShortURL(
+	ctx context.Context,
+	shortURL, originalURL, userID string,
+	limits repository.URLLimits,
+) (string, error) {
 	uid := lib.GenerateUUID()
-	existingShortURL, err := s.storage.CreateShortURL(uid, shortURL, originalURL)
+	storedShortURL, err := s.storage.CreateShortURL(ctx, uid, shortURL, originalURL, userID, limits)
 	if err != nil {
 		if errors.Is(err, repository.ErrDuplicateURL) {
-			return existingShortURL, repository.ErrDuplicateURL
+			// The error carries the existing short code; return it as well.
+			return storedShortURL, err
 		}
 		return "", err
 	}
-	return existingShortURL, nil
+	s.log(ctx).Debug("short URL created", zap.String("shortURL", storedShortURL), zap.String("originalURL", originalURL))
+	return storedShortURL, nil
 }
 
-func (s *URLService) ShortenBatchURLs(requests []models.BatchShortenRequest) ([]models.BatchShortenResponse, error) {
-	var batchResponse []models.BatchShortenResponse
+// prepareURL normalizes a URL and checks its host against the policy.
+func (s *URLService) prepareURL(ctx context.Context, rawURL string) (string, error) {
+	normalized, err := s.urls.Normalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkPolicy(normalized); err != nil {
+		s.log(ctx).Info("blocked URL", zap.String("originalURL", normalized), zap.Error(err))
+		return "", err
+	}
+	return normalized, nil
+}
+
+func (s *URLService) checkPolicy(rawURL string) error {
+	if s.policy == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return invalidURL(ReasonMalformed, "cannot parse URL")
+	}
+	return s.policy.Check(u.Hostname())
+}
+
+// createGeneratedShortURL stores the URL under a generated short code,
+// generating a new one when the code is already taken.
+func (s *URLService) createGeneratedShortURL(
+	ctx context.Context,
+	originalURL, userID string,
+	limits repository.URLLimits,
+) (string, error) {
+	var err error
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		var shortURL string
+		shortURL, err = s.codes.Generate(originalURL, attempt)
+		if err != nil {
+			return "", err
+		}
 
-	for _, req := range requests {
-		shortUUID := uuid.New().String()
+		var stored string
+		stored, err = s.createShortURL(ctx, shortURL, originalURL, userID, limits)
+		if !errors.Is(err, repository.ErrShortURLTaken) {
+			return stored, err
+		}
+		s.log(ctx).Debug("generated short code collided", zap.String("shortURL", shortURL), zap.Int("attempt", attempt))
+	}
+	return "", fmt.Errorf("%w after %d attempts", ErrNoFreeCode, maxCodeAttempts)
+}
 
-		shortURL, err := s.CreateShortURL(shortUUID, req.OriginalURL)
+// ShortenBatchURLs validates every request before storing any, then stores
+// the whole batch with one CreateBatchURLs call, so either all URLs are
+// shortened or none of them.
+func (s *URLService) ShortenBatchURLs(
+	ctx context.Context,
+	requests []models.BatchShortenRequest,
+	userID string,
+) ([]models.BatchShortenResponse, error) {
+	rows := make([]repository.BatchURLRequest, len(requests))
+	generated := make([]bool, len(requests))
+
+	now := time.Now()
+	for i, req := range requests {
+		opts := ShortenOptions{
+			Alias:     req.Alias,
+			ExpiresAt: req.ExpiresAt,
+			TTL:       time.Duration(req.TTL) * time.Second,
+			MaxClicks: req.MaxClicks,
+		}
+		limits, err := opts.limits(now)
 		if err != nil {
-			s.logger.Error(
-				"failed to create batch short URL",
-				zap.String("originalURL", req.OriginalURL),
-				zap.Error(err),
-			)
 			return nil, err
 		}
+		originalURL, err := s.prepareURL(ctx, req.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Alias != "" {
+			if err := ValidateAlias(opts.Alias); err != nil {
+				return nil, err
+			}
+		}
 
-		batchResponse = append(batchResponse, models.BatchShortenResponse{
-			CorrelationID: req.CorrelationID,
-			ShortURL:      s.baseURL + "/" + shortURL,
-		})
+		rows[i] = repository.BatchURLRequest{
+			UUID:        lib.GenerateUUID(),
+			ShortURL:    opts.Alias,
+			OriginalURL: originalURL,
+			UserID:      userID,
+			URLLimits:   limits,
+		}
+		generated[i] = opts.Alias == ""
 	}
 
+	if err := s.createBatch(ctx, rows, generated); err != nil {
+		s.log(ctx).Error("failed to create batch short URLs", zap.Int("size", len(rows)), zap.Error(err))
+		return nil, err
+	}
+
+	batchResponse := make([]models.BatchShortenResponse, len(rows))
+	for i, row := range rows {
+		batchResponse[i] = models.BatchShortenResponse{
+			CorrelationID: requests[i].CorrelationID,
+			ShortURL:      s.baseURL + "/" + row.ShortURL,
+		}
+	}
 	return batchResponse, nil
 }
 
-func (s *URLService) GetOriginalURL(shortURL string) (string, error) {
-	originalURL, err := s.storage.GetOriginalURL(shortURL)
+// createBatch stores the rows, generating short codes for those marked as
+// generated and generating them anew when the batch hits a taken code.
+func (s *URLService) createBatch(ctx context.Context, rows []repository.BatchURLRequest, generated []bool) error {
+	aliased := slices.Contains(generated, false)
+	if !slices.Contains(generated, true) {
+		_, err := s.storage.CreateBatchURLs(ctx, rows)
+		return err
+	}
+
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		for i := range rows {
+			if !generated[i] {
+				continue
+			}
+			shortURL, err := s.codes.Generate(rows[i].OriginalURL, attempt)
+			if err != nil {
+				return err
+			}
+			rows[i].ShortURL = shortURL
+		}
+
+		_, err := s.storage.CreateBatchURLs(ctx, rows)
+		if !errors.Is(err, repository.ErrShortURLTaken) {
+			return err
+		}
+		s.log(ctx).Debug("generated batch short code collided", zap.Int("attempt", attempt))
+	}
+	if aliased {
+		// A taken alias is the likelier cause, and the caller's to fix.
+		return repository.ErrShortURLTaken
+	}
+	return fmt.Errorf("%w after %d attempts", ErrNoFreeCode, maxCodeAttempts)
+}
+
+// GetOriginalURL resolves a short code. URLs whose host the policy has
+// blocked since they were shortened no longer resolve.
+func (s *URLService) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
+	originalURL, err := s.storage.GetOriginalURL(ctx, shortURL)
 	if err != nil {
-		return "", errors.New("URL not found")
+		return "", err
+	}
+	if err := s.checkPolicy(originalURL); err != nil {
+		s.log(ctx).Info("blocked redirect", zap.String("shortURL", shortURL), zap.Error(err))
+		return "", err
 	}
 	return originalURL, nil
 }
 
-func (s *URLService) Ping() error {
-	return s.storage.Ping()
+func (s *URLService) GetUserURLs(ctx context.Context, userID string) ([]models.UserURLResponse, error) {
+	records, err := s.storage.GetUserURLs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userURLs := make([]models.UserURLResponse, 0, len(records))
+	for _, record := range records {
+		userURLs = append(userURLs, models.UserURLResponse{
+			ShortURL:    s.baseURL + "/" + record.ShortURL,
+			OriginalURL: record.OriginalURL,
+		})
+	}
+	return userURLs, nil
+}
+
+// DeleteURLs queues the user's short URLs for deletion and returns without
+// waiting for the storage to be updated. The context only bounds the wait for
+// room in the queue.
+func (s *URLService) DeleteURLs(ctx context.Context, userID string, shortURLs []string) error {
+	s.log(ctx).Debug("queueing URL deletion", zap.Int("count", len(shortURLs)))
+	return s.deleter.enqueue(ctx, userID, shortURLs)
+}
+
+// RecordClick queues a redirect event for statistics. It never blocks and the
+// event is written after the request ends, so the context is not used for the
+// write; the client IP is stored only as a hash.
+func (s *URLService) RecordClick(ctx context.Context, shortURL, referrer, userAgent, clientIP string) {
+	event := repository.ClickEvent{
+		ShortURL:  shortURL,
+		Timestamp: time.Now().UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+	}
+	if clientIP != "" {
+		sum := sha256.Sum256([]byte(clientIP))
+		event.IPHash = hex.EncodeToString(sum[:])
+	}
+	s.clicks.record(event)
+}
+
+func (s *URLService) GetURLStats(ctx context.Context, shortURL string) (models.URLStatsResponse, error) {
+	stats, err := s.storage.GetURLStats(ctx, shortURL)
+	if err != nil {
+		return models.URLStatsResponse{}, err
+	}
+
+	response := models.URLStatsResponse{
+		TotalClicks:    stats.TotalClicks,
+		UniqueVisitors: stats.UniqueVisitors,
+		Daily:          make([]models.DailyClicks, 0, len(stats.Daily)),
+	}
+	for _, day := range stats.Daily {
+		response.Daily = append(response.Daily, models.DailyClicks{
+			Date:   day.Date.Format(time.DateOnly),
+			Clicks: day.Clicks,
+		})
+	}
+	return response, nil
+}
+
+// GetInternalStats reports how many URLs are stored and how many users own them.
+func (s *URLService) GetInternalStats(ctx context.Context) (models.InternalStatsResponse, error) {
+	urls, err := s.storage.CountURLs(ctx)
+	if err != nil {
+		return models.InternalStatsResponse{}, err
+	}
+	users, err := s.storage.CountUsers(ctx)
+	if err != nil {
+		return models.InternalStatsResponse{}, err
+	}
+	return models.InternalStatsResponse{URLs: urls, Users: users}, nil
+}
+
+func (s *URLService) Ping(ctx context.Context) error {
+	return s.storage.Ping(ctx)
+}
+
+// Close flushes pending background work. It should be called once the HTTP
+// server has stopped accepting requests.
+func (s *URLService) Close() {
+	s.deleter.close()
+	s.clicks.close()
 }
@@ -0,0 +1,46 @@
+package transfer
+
+import (
+	"context"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+// DefaultPageSize is how many records are read per ListURLs call on export.
+const DefaultPageSize = 1000
+
+// Export writes every URL that can still be resolved, reading the storage a
+// page at a time so memory use does not grow with its size. It returns the
+// number of rows written.
+func Export(ctx context.Context, storage repository.Storage, w RowWriter, pageSize int) (int, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	written := 0
+	after := ""
+	for {
+		records, err := storage.ListURLs(ctx, after, pageSize)
+		if err != nil {
+			return written, err
+		}
+
+		now := time.Now()
+		for _, record := range records {
+			if record.DeletedFlag || record.Expired(record.Clicks, now) {
+				continue
+			}
+			row := Row{URL: record.OriginalURL, Alias: record.ShortURL, Owner: record.UserID}
+			if err := w.Write(row); err != nil {
+				return written, err
+			}
+			written++
+		}
+
+		if len(records) < pageSize {
+			return written, w.Flush()
+		}
+		after = records[len(records)-1].ShortURL
+	}
+}
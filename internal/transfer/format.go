@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// csvHeader names the CSV columns in the order written by export and
+// assumed for headerless input.
+var csvHeader = []string{"url", "alias", "owner"}
+
+// maxLineSize bounds a single JSON-lines row.
+const maxLineSize = 1 << 20
+
+// Row is one link of an import or export file. Alias and Owner are optional
+// on import; export always writes the stored short code as the alias.
+type Row struct {
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+// FormatFromPath guesses the format from a file extension.
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %q, use -format csv or jsonl", path)
+	}
+}
+
+// RowError reports a row that could not be read or imported.
+type RowError struct {
+	Line int
+	URL  string
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d (%s): %v", e.Line, e.URL, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowReader streams rows from an import file. Next returns io.EOF after the
+// last row; a *RowError means only that row is malformed and reading can go on.
+type RowReader interface {
+	Next() (Row, int, error)
+}
+
+func NewRowReader(r io.Reader, format string) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		reader.ReuseRecord = true
+		return &csvReader{reader: reader, columns: map[string]int{"url": 0, "alias": 1, "owner": 2}}, nil
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &jsonlReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	started bool
+}
+
+func (c *csvReader) Next() (Row, int, error) {
+	for {
+		record, err := c.reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return Row{}, parseErr.StartLine, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+			}
+			return Row{}, 0, err
+		}
+		line, _ := c.reader.FieldPos(0)
+
+		first := !c.started
+		c.started = true
+		if first && isCSVHeader(record) {
+			c.columns = make(map[string]int, len(record))
+			for i, name := range record {
+				c.columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			if _, ok := c.columns["url"]; !ok {
+				return Row{}, line, errors.New("CSV header has no url column")
+			}
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row := Row{URL: c.field(record, "url"), Alias: c.field(record, "alias"), Owner: c.field(record, "owner")}
+		return row, line, nil
+	}
+}
+
+func (c *csvReader) field(record []string, name string) string {
+	i, ok := c.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// isCSVHeader reports whether the first record names columns rather than
+// holding a link.
+func isCSVHeader(record []string) bool {
+	for _, field := range record {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "url", "original_url":
+			return true
+		}
+	}
+	return false
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (j *jsonlReader) Next() (Row, int, error) {
+	for j.scanner.Scan() {
+		j.line++
+		data := j.scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		var row Row
+		if err := json.Unmarshal(data, &row); err != nil {
+			return Row{}, j.line, &RowError{Line: j.line, Err: err}
+		}
+		return row, j.line, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Row{}, j.line, err
+	}
+	return Row{}, j.line, io.EOF
+}
+
+// RowWriter streams rows to an export file. Flush must be called after the
+// last row.
+type RowWriter interface {
+	Write(Row) error
+	Flush() error
+}
+
+func NewRowWriter(w io.Writer, format string) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) Write(row Row) error {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		if err := c.writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	return c.writer.Write([]string{row.URL, row.Alias, row.Owner})
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		if err := c.writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type jsonlWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (j *jsonlWriter) Write(row Row) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.buffered.Flush()
+}
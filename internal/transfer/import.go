@@ -0,0 +1,207 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultChunkSize is how many rows are stored per CreateBatchURLs call.
+	DefaultChunkSize = 500
+	// maxCodeAttempts bounds how many generated codes are tried for a row
+	// whose code collided.
+	maxCodeAttempts = 5
+	// maxOwnerLength is the size of the user_id column.
+	maxOwnerLength = 36
+)
+
+// ErrInvalidOwner rejects a row whose owner the storage cannot hold.
+var ErrInvalidOwner = errors.New("invalid owner")
+
+// ImportReport counts the rows of an import.
+type ImportReport struct {
+	Read     int
+	Imported int
+	Failed   int
+}
+
+// Importer stores rows in chunks. A chunk that conflicts with stored URLs is
+// retried row by row, so only the conflicting rows fail.
+type Importer struct {
+	Storage repository.Storage
+	Codes   lib.CodeGenerator
+	URLs    service.URLNormalizer
+	// Policy, if set, rejects rows whose host it blocks.
+	Policy service.HostPolicy
+	Logger *zap.Logger
+	// ChunkSize defaults to DefaultChunkSize.
+	ChunkSize int
+	// OnFailure, if set, is called for every row that is not imported.
+	OnFailure func(*RowError)
+}
+
+// pendingRow is a validated row waiting to be stored.
+type pendingRow struct {
+	line      int
+	request   repository.BatchURLRequest
+	generated bool
+}
+
+// Import reads rows until io.EOF. Failed rows are reported and skipped; the
+// returned error is reserved for failures of the reader or the storage.
+func (im *Importer) Import(ctx context.Context, rows RowReader) (ImportReport, error) {
+	chunkSize := im.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var report ImportReport
+	chunk := make([]pendingRow, 0, chunkSize)
+	for {
+		row, line, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report.Read++
+			im.fail(&report, rowErr)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Read++
+
+		pending, err := im.prepare(row, line)
+		if err != nil {
+			im.fail(&report, &RowError{Line: line, URL: row.URL, Err: err})
+			continue
+		}
+		chunk = append(chunk, pending)
+		if len(chunk) == chunkSize {
+			if err := im.store(ctx, chunk, &report); err != nil {
+				return report, err
+			}
+			chunk = chunk[:0]
+		}
+	}
+
+	if len(chunk) > 0 {
+		if err := im.store(ctx, chunk, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// prepare validates a row. Aliases are checked as existing short codes rather
+// than as new aliases, so codes exported from this service import unchanged.
+func (im *Importer) prepare(row Row, line int) (pendingRow, error) {
+	originalURL, err := im.URLs.Normalize(row.URL)
+	if err != nil {
+		return pendingRow{}, err
+	}
+	if im.Policy != nil {
+		u, err := url.Parse(originalURL)
+		if err != nil {
+			return pendingRow{}, err
+		}
+		if err := im.Policy.Check(u.Hostname()); err != nil {
+			return pendingRow{}, err
+		}
+	}
+	if len(row.Owner) > maxOwnerLength {
+		return pendingRow{}, fmt.Errorf("%w: longer than %d characters", ErrInvalidOwner, maxOwnerLength)
+	}
+
+	pending := pendingRow{
+		line: line,
+		request: repository.BatchURLRequest{
+			UUID:        lib.GenerateUUID(),
+			ShortURL:    row.Alias,
+			OriginalURL: originalURL,
+			UserID:      row.Owner,
+		},
+	}
+	if row.Alias != "" {
+		return pending, service.ValidateShortCode(row.Alias)
+	}
+
+	pending.generated = true
+	pending.request.ShortURL, err = im.Codes.Generate(originalURL, 0)
+	return pending, err
+}
+
+// store writes a chunk in one batch and falls back to single inserts when the
+// batch conflicts with stored URLs or within itself.
+func (im *Importer) store(ctx context.Context, chunk []pendingRow, report *ImportReport) error {
+	requests := make([]repository.BatchURLRequest, len(chunk))
+	for i, pending := range chunk {
+		requests[i] = pending.request
+	}
+
+	_, err := im.Storage.CreateBatchURLs(ctx, requests)
+	if err == nil {
+		report.Imported += len(chunk)
+		return nil
+	}
+	if !errors.Is(err, repository.ErrShortURLTaken) && !errors.Is(err, repository.ErrDuplicateURL) {
+		return fmt.Errorf("failed to store rows %d-%d: %w", chunk[0].line, chunk[len(chunk)-1].line, err)
+	}
+
+	im.logger().Debug("chunk conflicts with stored URLs, importing row by row",
+		zap.Int("firstLine", chunk[0].line), zap.Error(err))
+	for _, pending := range chunk {
+		err := im.storeRow(ctx, pending)
+		switch {
+		case err == nil:
+			report.Imported++
+		case errors.Is(err, repository.ErrShortURLTaken), errors.Is(err, repository.ErrDuplicateURL):
+			im.fail(report, &RowError{Line: pending.line, URL: pending.request.OriginalURL, Err: err})
+		default:
+			return fmt.Errorf("failed to store line %d: %w", pending.line, err)
+		}
+	}
+	return nil
+}
+
+// storeRow inserts a single row, trying new codes for generated ones that collide.
+func (im *Importer) storeRow(ctx context.Context, pending pendingRow) error {
+	req := pending.request
+	var err error
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		if attempt > 0 {
+			if req.ShortURL, err = im.Codes.Generate(req.OriginalURL, attempt); err != nil {
+				return err
+			}
+		}
+		_, err = im.Storage.CreateShortURL(ctx, req.UUID, req.ShortURL, req.OriginalURL, req.UserID, req.URLLimits)
+		if !pending.generated || !errors.Is(err, repository.ErrShortURLTaken) {
+			return err
+		}
+	}
+	return err
+}
+
+func (im *Importer) fail(report *ImportReport, err *RowError) {
+	report.Failed++
+	if im.OnFailure != nil {
+		im.OnFailure(err)
+	}
+}
+
+func (im *Importer) logger() *zap.Logger {
+	if im.Logger == nil {
+		return zap.NewNop()
+	}
+	return im.Logger
+}
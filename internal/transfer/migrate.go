@@ -0,0 +1,356 @@
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+)
+
+var (
+	// ErrConflict means the destination holds a different record under a
+	// short code being copied, or the same URL under another code.
+	ErrConflict = errors.New("destination conflicts with source")
+	// ErrMismatch means a verified destination differs from its source.
+	ErrMismatch = errors.New("destination does not match source")
+)
+
+// CopyReport counts the records of a storage copy and the click events copied
+// with them. Counter is the destination's short-code counter after the copy.
+type CopyReport struct {
+	Copied  int
+	Skipped int
+	Clicks  int
+	Counter uint64
+}
+
+// VerifyReport describes the compared records and click events of both
+// storages.
+type VerifyReport struct {
+	Count             int
+	Found             int
+	Clicks            int
+	ClicksFound       int
+	SourceSum         string
+	DestinationSum    string
+	FirstMismatchCode string
+	// SourceCounter and DestinationCounter are the short-code counters.
+	SourceCounter      uint64
+	DestinationCounter uint64
+}
+
+// Migration copies every record of one storage into another, keeping short
+// codes, UUIDs, owners, limits, visit counts, deletions, click history and the
+// short-code counter.
+// The source should not take writes while it runs.
+type Migration struct {
+	Source      repository.Storage
+	Destination repository.Storage
+	// PageSize defaults to DefaultPageSize.
+	PageSize int
+	// OnProgress, if set, is called after every page.
+	OnProgress func(CopyReport)
+}
+
+// Copy walks the source in short code order and stores the records and click
+// events that the destination does not have yet. Records and clicks already
+// copied by an interrupted run are skipped, so Copy can simply be run again
+// to resume.
+//
+// The destination counter is first raised to the source's, so that it never
+// generates the codes being copied or codes the source has already issued.
+func (m *Migration) Copy(ctx context.Context) (CopyReport, error) {
+	var report CopyReport
+	counter, err := m.Source.AdvanceCounter(ctx, 0)
+	if err != nil {
+		return report, fmt.Errorf("failed to read source counter: %w", err)
+	}
+	if report.Counter, err = m.Destination.AdvanceCounter(ctx, counter); err != nil {
+		return report, fmt.Errorf("failed to advance destination counter: %w", err)
+	}
+
+	err = m.walk(ctx, func(p pageRange) error {
+		page, existing := p.records, p.existing
+		requests := make([]repository.BatchURLRequest, 0, len(page))
+		for _, record := range page {
+			stored, ok := existing[record.ShortURL]
+			if !ok {
+				requests = append(requests, batchRequest(record))
+				continue
+			}
+			if canonical(stored) != canonical(record) {
+				return fmt.Errorf("%w: short code %q", ErrConflict, record.ShortURL)
+			}
+			report.Skipped++
+		}
+
+		if len(requests) > 0 {
+			_, err := m.Destination.CreateBatchURLs(ctx, requests)
+			if errors.Is(err, repository.ErrDuplicateURL) || errors.Is(err, repository.ErrShortURLTaken) {
+				return fmt.Errorf("%w: %v", ErrConflict, err)
+			}
+			if err != nil {
+				return err
+			}
+			report.Copied += len(requests)
+		}
+
+		clicks, err := m.missingClicks(p)
+		if err != nil {
+			return err
+		}
+		// Each page's clicks are stored in one call, which either stores all
+		// of them or none, so a resumed run never finds a URL half copied.
+		if len(clicks) > 0 {
+			if err := m.Destination.RecordClicks(ctx, clicks); err != nil {
+				return err
+			}
+			report.Clicks += len(clicks)
+		}
+
+		if m.OnProgress != nil {
+			m.OnProgress(report)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// Verify compares the records and click events of the source with those of
+// the destination under the same short codes, by count and by a checksum over
+// both sides. It returns ErrMismatch when they differ or when the destination
+// counter is behind the source's.
+func (m *Migration) Verify(ctx context.Context) (VerifyReport, error) {
+	var report VerifyReport
+	var err error
+	if report.SourceCounter, err = m.Source.AdvanceCounter(ctx, 0); err != nil {
+		return report, fmt.Errorf("failed to read source counter: %w", err)
+	}
+	if report.DestinationCounter, err = m.Destination.AdvanceCounter(ctx, 0); err != nil {
+		return report, fmt.Errorf("failed to read destination counter: %w", err)
+	}
+
+	sourceSum, destinationSum := sha256.New(), sha256.New()
+	err = m.walk(ctx, func(p pageRange) error {
+		page, existing := p.records, p.existing
+		for _, record := range page {
+			report.Count++
+			writeRecord(sourceSum, record)
+
+			stored, ok := existing[record.ShortURL]
+			if ok {
+				report.Found++
+				writeRecord(destinationSum, stored)
+			}
+			if report.FirstMismatchCode == "" && (!ok || canonical(stored) != canonical(record)) {
+				report.FirstMismatchCode = record.ShortURL
+			}
+		}
+
+		sourceClicks, destinationClicks := groupClicks(p.sourceClicks), groupClicks(p.destinationClicks)
+		for _, record := range page {
+			source, destination := sourceClicks[record.ShortURL], destinationClicks[record.ShortURL]
+			report.Clicks += len(source)
+			report.ClicksFound += len(destination)
+			for _, line := range clickLines(source) {
+				writeLine(sourceSum, line)
+			}
+			for _, line := range clickLines(destination) {
+				writeLine(destinationSum, line)
+			}
+			if report.FirstMismatchCode == "" && !sameClicks(source, destination) {
+				report.FirstMismatchCode = record.ShortURL
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.SourceSum = hex.EncodeToString(sourceSum.Sum(nil))
+	report.DestinationSum = hex.EncodeToString(destinationSum.Sum(nil))
+	if report.Found != report.Count || report.ClicksFound != report.Clicks ||
+		report.SourceSum != report.DestinationSum {
+		return report, fmt.Errorf("%w: %d of %d records and %d of %d clicks found, first difference at %q",
+			ErrMismatch, report.Found, report.Count, report.ClicksFound, report.Clicks, report.FirstMismatchCode)
+	}
+	if report.DestinationCounter < report.SourceCounter {
+		return report, fmt.Errorf("%w: destination counter %d is behind source counter %d",
+			ErrMismatch, report.DestinationCounter, report.SourceCounter)
+	}
+	return report, nil
+}
+
+// missingClicks returns the source click events of the page's URLs that have
+// no clicks in the destination yet. A URL whose clicks differ on both sides
+// is an ErrConflict.
+func (m *Migration) missingClicks(p pageRange) ([]repository.ClickEvent, error) {
+	destination := groupClicks(p.destinationClicks)
+	var missing []repository.ClickEvent
+	for short, source := range groupClicks(p.sourceClicks) {
+		stored := destination[short]
+		switch {
+		case len(stored) == 0:
+			missing = append(missing, source...)
+		case !sameClicks(source, stored):
+			return nil, fmt.Errorf("%w: clicks of short code %q", ErrConflict, short)
+		}
+	}
+	return missing, nil
+}
+
+// pageRange is a page of source records with the destination records and the
+// click events of both sides in the same range of short codes.
+type pageRange struct {
+	records           []repository.URLRecord
+	existing          map[string]repository.URLRecord
+	sourceClicks      []repository.ClickEvent
+	destinationClicks []repository.ClickEvent
+}
+
+// walk calls fn with every page of source records, in short code order.
+func (m *Migration) walk(ctx context.Context, fn func(pageRange) error) error {
+	pageSize := m.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	after := ""
+	for {
+		records, err := m.Source.ListURLs(ctx, after, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read source: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		last := records[len(records)-1].ShortURL
+		p := pageRange{records: records}
+		if p.sourceClicks, err = m.Source.ListClicks(ctx, after, last); err != nil {
+			return fmt.Errorf("failed to read source clicks: %w", err)
+		}
+		if p.existing, err = m.destinationRange(ctx, after, last, pageSize); err != nil {
+			return fmt.Errorf("failed to read destination: %w", err)
+		}
+		if p.destinationClicks, err = m.Destination.ListClicks(ctx, after, last); err != nil {
+			return fmt.Errorf("failed to read destination clicks: %w", err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+
+		if len(records) < pageSize {
+			return nil
+		}
+		after = last
+	}
+}
+
+// destinationRange returns the destination records with short codes after
+// from and up to and including to.
+func (m *Migration) destinationRange(
+	ctx context.Context,
+	from, to string,
+	pageSize int,
+) (map[string]repository.URLRecord, error) {
+	records := make(map[string]repository.URLRecord)
+	after := from
+	for {
+		page, err := m.Destination.ListURLs(ctx, after, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page {
+			if record.ShortURL > to {
+				return records, nil
+			}
+			records[record.ShortURL] = record
+		}
+		if len(page) < pageSize {
+			return records, nil
+		}
+		after = page[len(page)-1].ShortURL
+	}
+}
+
+func batchRequest(record repository.URLRecord) repository.BatchURLRequest {
+	return repository.BatchURLRequest{
+		UUID:        record.UUID,
+		ShortURL:    record.ShortURL,
+		OriginalURL: record.OriginalURL,
+		UserID:      record.UserID,
+		URLLimits:   record.URLLimits,
+		Clicks:      record.Clicks,
+		Deleted:     record.DeletedFlag,
+	}
+}
+
+// canonical renders a record the same way whichever backend it was read
+// from. Expiry times are cut to the microsecond precision of Postgres.
+func canonical(record repository.URLRecord) string {
+	expiresAt := ""
+	if record.ExpiresAt != nil {
+		expiresAt = record.ExpiresAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	}
+	return strconv.Quote(record.UUID) + " " +
+		strconv.Quote(record.ShortURL) + " " +
+		strconv.Quote(record.OriginalURL) + " " +
+		strconv.Quote(record.UserID) + " " +
+		strconv.FormatBool(record.DeletedFlag) + " " +
+		expiresAt + " " +
+		strconv.Itoa(record.MaxClicks) + " " +
+		strconv.Itoa(record.Clicks)
+}
+
+func writeRecord(h hash.Hash, record repository.URLRecord) {
+	writeLine(h, canonical(record))
+}
+
+func writeLine(h hash.Hash, line string) {
+	h.Write([]byte(line))
+	h.Write([]byte{'\n'})
+}
+
+// canonicalClick renders a click event the same way whichever backend it was
+// read from.
+func canonicalClick(event repository.ClickEvent) string {
+	return strconv.Quote(event.ShortURL) + " " +
+		event.Timestamp.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano) + " " +
+		strconv.Quote(event.Referrer) + " " +
+		strconv.Quote(event.UserAgent) + " " +
+		strconv.Quote(event.IPHash)
+}
+
+// groupClicks returns the click events of each short URL.
+func groupClicks(events []repository.ClickEvent) map[string][]repository.ClickEvent {
+	groups := make(map[string][]repository.ClickEvent)
+	for _, event := range events {
+		groups[event.ShortURL] = append(groups[event.ShortURL], event)
+	}
+	return groups
+}
+
+// clickLines returns the canonical events sorted, so that backends ordering
+// clicks of the same time differently compare equal.
+func clickLines(events []repository.ClickEvent) []string {
+	lines := make([]string, len(events))
+	for i, event := range events {
+		lines[i] = canonicalClick(event)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func sameClicks(a, b []repository.ClickEvent) bool {
+	return slices.Equal(clickLines(a), clickLines(b))
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/transfer"
+)
+
+func seedSource(t *testing.T, storage repository.Storage, n int) {
+	t.Helper()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Round(0)
+	for i := 0; i < n; i++ {
+		limits := repository.URLLimits{}
+		if i%3 == 0 {
+			limits = repository.URLLimits{ExpiresAt: &expiresAt, MaxClicks: 10}
+		}
+		short := fmt.Sprintf("code%02d", i)
+		if _, err := storage.CreateShortURL(ctx, fmt.Sprintf("uuid-%d", i), short,
+			"https://example.com/"+short, "owner", limits); err != nil {
+			t.Fatalf("Failed to seed storage: %v", err)
+		}
+	}
+	_, _ = storage.GetOriginalURL(ctx, "code00")
+	_ = storage.DeleteURLs(ctx, map[string][]string{"owner": {"code01"}})
+}
+
+func seedClicks(t *testing.T, storage repository.Storage, shortURL string, n int) {
+	t.Helper()
+	events := make([]repository.ClickEvent, n)
+	for i := range events {
+		events[i] = repository.ClickEvent{
+			ShortURL:  shortURL,
+			Timestamp: time.Date(2024, 5, 1, 12, i, 0, 0, time.UTC),
+			Referrer:  "https://ref.example/",
+			IPHash:    fmt.Sprintf("ip-%d", i),
+		}
+	}
+	if err := storage.RecordClicks(context.Background(), events); err != nil {
+		t.Fatalf("Failed to seed clicks: %v", err)
+	}
+}
+
+func TestMigration_CopyAndResume(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewInMemoryStorage()
+	seedSource(t, source, 10)
+	seedClicks(t, source, "code00", 2)
+	seedClicks(t, source, "code05", 3)
+	if _, err := source.ReserveCounter(ctx, 200); err != nil {
+		t.Fatalf("Failed to reserve counter: %v", err)
+	}
+
+	destination, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "urls.json"), repository.FileStorageOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open file storage: %v", err)
+	}
+	defer destination.Close()
+
+	// An earlier run stopped after storing the first records.
+	partial, _ := source.ListURLs(ctx, "", 4)
+	requests := make([]repository.BatchURLRequest, len(partial))
+	for i, record := range partial {
+		requests[i] = repository.BatchURLRequest{
+			UUID: record.UUID, ShortURL: record.ShortURL, OriginalURL: record.OriginalURL, UserID: record.UserID,
+			URLLimits: record.URLLimits, Clicks: record.Clicks, Deleted: record.DeletedFlag,
+		}
+	}
+	if _, err := destination.CreateBatchURLs(ctx, requests); err != nil {
+		t.Fatalf("Failed to seed destination: %v", err)
+	}
+	seedClicks(t, destination, "code00", 2)
+
+	migration := &transfer.Migration{Source: source, Destination: destination, PageSize: 3}
+	report, err := migration.Copy(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report != (transfer.CopyReport{Copied: 6, Skipped: 4, Clicks: 3, Counter: 200}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	verified, err := migration.Verify(ctx)
+	if err != nil || verified.Count != 10 || verified.Clicks != 5 || verified.SourceSum != verified.DestinationSum {
+		t.Fatalf("Expected the storages to match, got %+v, %v", verified, err)
+	}
+
+	if _, err := destination.LookupURL(ctx, "code01"); !errors.Is(err, repository.ErrDeleted) {
+		t.Errorf("Expected the deletion to be copied, got %v", err)
+	}
+	if record, _ := destination.LookupURL(ctx, "code00"); record.Clicks != 1 || record.UUID != "uuid-0" {
+		t.Errorf("Expected the visit count and UUID to be copied, got %+v", record)
+	}
+	if stats, err := destination.GetURLStats(ctx, "code05"); err != nil || stats.TotalClicks != 3 {
+		t.Errorf("Expected the click history to be copied, got %+v, %v", stats, err)
+	}
+
+	// A second run finds everything in place.
+	if report, err := migration.Copy(ctx); err != nil || report != (transfer.CopyReport{Skipped: 10, Counter: 200}) {
+		t.Errorf("Expected nothing left to copy, got %+v, %v", report, err)
+	}
+	if first, err := destination.ReserveCounter(ctx, 1); err != nil || first != 200 {
+		t.Errorf("Expected the destination counter to continue at 200, got %d, %v", first, err)
+	}
+}
+
+func TestMigration_VerifyClicks(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewInMemoryStorage()
+	seedSource(t, source, 3)
+	seedClicks(t, source, "code02", 2)
+
+	destination := repository.NewInMemoryStorage()
+	migration := &transfer.Migration{Source: source, Destination: destination}
+	if _, err := migration.Copy(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	seedClicks(t, destination, "code02", 1)
+	report, err := migration.Verify(ctx)
+	if !errors.Is(err, transfer.ErrMismatch) || report.FirstMismatchCode != "code02" {
+		t.Errorf("Expected a click mismatch at code02, got %+v, %v", report, err)
+	}
+	if _, err := migration.Copy(ctx); !errors.Is(err, transfer.ErrConflict) {
+		t.Errorf("Expected differing clicks to conflict, got %v", err)
+	}
+}
+
+func TestMigration_Conflicts(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewInMemoryStorage()
+	seedSource(t, source, 3)
+
+	destination := repository.NewInMemoryStorage()
+	if _, err := destination.CreateShortURL(ctx, "other", "code02", "https://example.org/", "",
+		repository.URLLimits{}); err != nil {
+		t.Fatalf("Failed to seed storage: %v", err)
+	}
+
+	migration := &transfer.Migration{Source: source, Destination: destination}
+	if _, err := migration.Copy(ctx); !errors.Is(err, transfer.ErrConflict) {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	if _, err := migration.Verify(ctx); !errors.Is(err, transfer.ErrMismatch) {
+		t.Errorf("Expected a mismatch, got %v", err)
+	}
+}
+
+func TestMigration_Counter(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewInMemoryStorage()
+	if _, err := source.ReserveCounter(ctx, 100); err != nil {
+		t.Fatalf("Failed to reserve counter: %v", err)
+	}
+
+	destination := repository.NewInMemoryStorage()
+	migration := &transfer.Migration{Source: source, Destination: destination}
+	if report, err := migration.Verify(ctx); !errors.Is(err, transfer.ErrMismatch) || report.DestinationCounter != 0 {
+		t.Errorf("Expected a counter mismatch, got %+v, %v", report, err)
+	}
+
+	// A destination that is ahead keeps its counter.
+	if _, err := destination.ReserveCounter(ctx, 300); err != nil {
+		t.Fatalf("Failed to reserve counter: %v", err)
+	}
+	if report, err := migration.Copy(ctx); err != nil || report.Counter != 300 {
+		t.Errorf("Expected the destination counter to stay at 300, got %+v, %v", report, err)
+	}
+	if report, err := migration.Verify(ctx); err != nil || report.SourceCounter != 100 {
+		t.Errorf("Expected the storages to match, got %+v, %v", report, err)
+	}
+}
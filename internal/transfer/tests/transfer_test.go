@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/policy"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	servicemocks "github.com/hairutdin/url-shortener/internal/service/mocks"
+	"github.com/hairutdin/url-shortener/internal/transfer"
+)
+
+func newImporter(t *testing.T, storage repository.Storage, failures *[]*transfer.RowError) *transfer.Importer {
+	t.Helper()
+	codes, err := lib.NewCodeGenerator(lib.CodeStrategyRandom, 8, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create code generator: %v", err)
+	}
+	return &transfer.Importer{
+		Storage:   storage,
+		Codes:     codes,
+		ChunkSize: 2,
+		OnFailure: func(err *transfer.RowError) { *failures = append(*failures, err) },
+	}
+}
+
+func TestImport_CSV(t *testing.T) {
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+	if _, err := storage.CreateShortURL(ctx, "1", "taken", "https://old.example.com/", "", repository.URLLimits{}); err != nil {
+		t.Fatalf("Failed to seed storage: %v", err)
+	}
+
+	input := strings.Join([]string{
+		"owner,url,alias",
+		"alice,https://example.com/a,",
+		",https://example.com/b,mine",
+		",ftp://example.com/c,",
+		",https://example.com/d,taken",
+		"bob,https://example.com/e,",
+		",https://example.com/a,",
+	}, "\n")
+	rows, err := transfer.NewRowReader(strings.NewReader(input), transfer.FormatCSV)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var failures []*transfer.RowError
+	report, err := newImporter(t, storage, &failures).Import(ctx, rows)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report != (transfer.ImportReport{Read: 6, Imported: 3, Failed: 3}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	wantFailures := map[int]error{4: service.ErrInvalidURL, 5: repository.ErrShortURLTaken, 7: repository.ErrDuplicateURL}
+	for _, failure := range failures {
+		if want := wantFailures[failure.Line]; want == nil || !errors.Is(failure, want) {
+			t.Errorf("Unexpected failure %v", failure)
+		}
+	}
+
+	if record, err := storage.LookupURL(ctx, "mine"); err != nil || record.OriginalURL != "https://example.com/b" {
+		t.Errorf("Expected the alias to be kept, got %+v, %v", record, err)
+	}
+	if records, _ := storage.GetUserURLs(ctx, "bob"); len(records) != 1 {
+		t.Errorf("Expected the owner to be kept, got %+v", records)
+	}
+}
+
+func TestImport_ValidatesRows(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	storage := repository.NewInMemoryStorage()
+	mockPolicy := servicemocks.NewMockHostPolicy(ctrl)
+	mockPolicy.EXPECT().Check("example.com").Return(nil).AnyTimes()
+	mockPolicy.EXPECT().Check("phish.example").Return(&policy.BlockedError{Host: "phish.example", Rule: "deny"})
+
+	uuidCode := "0b9e4c1e-8f1a-4c55-9a55-2f1f6a4f0c3d"
+	input := strings.Join([]string{
+		"owner,url,alias",
+		",https://example.com/a," + uuidCode,
+		",https://phish.example/,",
+		strings.Repeat("x", 37) + ",https://example.com/b,",
+		",https://example.com/c,a b",
+	}, "\n")
+	rows, _ := transfer.NewRowReader(strings.NewReader(input), transfer.FormatCSV)
+
+	var failures []*transfer.RowError
+	importer := newImporter(t, storage, &failures)
+	importer.Policy = mockPolicy
+	report, err := importer.Import(ctx, rows)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report != (transfer.ImportReport{Read: 4, Imported: 1, Failed: 3}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	wantFailures := map[int]error{3: policy.ErrBlocked, 4: transfer.ErrInvalidOwner, 5: service.ErrInvalidAlias}
+	for _, failure := range failures {
+		if want := wantFailures[failure.Line]; want == nil || !errors.Is(failure, want) {
+			t.Errorf("Unexpected failure %v", failure)
+		}
+	}
+	if _, err := storage.LookupURL(ctx, uuidCode); err != nil {
+		t.Errorf("Expected a UUID code from the batch endpoint to be imported, got %v", err)
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewInMemoryStorage()
+	for _, short := range []string{"one", "two", "three"} {
+		if _, err := source.CreateShortURL(ctx, short, short, "https://example.com/"+short, "user",
+			repository.URLLimits{}); err != nil {
+			t.Fatalf("Failed to seed storage: %v", err)
+		}
+	}
+	_ = source.DeleteURLs(ctx, map[string][]string{"user": {"two"}})
+
+	for _, format := range []string{transfer.FormatCSV, transfer.FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			rows, _ := transfer.NewRowWriter(&buf, format)
+			written, err := transfer.Export(ctx, source, rows, 1)
+			if err != nil || written != 2 {
+				t.Fatalf("Expected 2 rows, got %d, %v", written, err)
+			}
+
+			target := repository.NewInMemoryStorage()
+			reader, _ := transfer.NewRowReader(&buf, format)
+			var failures []*transfer.RowError
+			report, err := newImporter(t, target, &failures).Import(ctx, reader)
+			if err != nil || report.Imported != 2 || len(failures) != 0 {
+				t.Fatalf("Expected 2 imported rows, got %+v, %v, %v", report, failures, err)
+			}
+			for _, short := range []string{"one", "three"} {
+				record, err := target.LookupURL(ctx, short)
+				if err != nil || record.OriginalURL != "https://example.com/"+short || record.UserID != "user" {
+					t.Errorf("Expected %s to be copied, got %+v, %v", short, record, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package client is a Go client for the URL shortener HTTP API.
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hairutdin/url-shortener/internal/models"
+)
+
+// Request and response types of the API.
+type (
+	ShortenRequest       = models.ShortenRequest
+	BatchShortenRequest  = models.BatchShortenRequest
+	BatchShortenResponse = models.BatchShortenResponse
+	UserURL              = models.UserURLResponse
+	URLStats             = models.URLStatsResponse
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 10 * time.Second
+	maxErrorBody        = 64 * 1024
+)
+
+// Options configure a Client. Zero values select the defaults.
+type Options struct {
+	// HTTPClient sends the requests. Its CheckRedirect is replaced so that
+	// Resolve can report redirect targets, and a cookie jar is added if it
+	// has none, since the server identifies users by cookie.
+	HTTPClient *http.Client
+	// MaxRetries bounds the retries of a failed request; negative disables them.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles after each.
+	RetryBackoff time.Duration
+	// Gzip compresses request bodies.
+	Gzip bool
+}
+
+// Client calls the shortener API. Requests that failed because of the
+// network or an overloaded server are retried with exponential backoff:
+// reads and deletions always, shorten requests only when the server
+// reports that it did not process them (429 and 503).
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+	gzip       bool
+}
+
+func New(baseURL string, opts Options) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("base URL %q: must be an absolute http or https URL", baseURL)
+	}
+
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if opts.HTTPClient != nil {
+		copied := *opts.HTTPClient
+		httpClient = &copied
+	}
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	if httpClient.Jar == nil {
+		httpClient.Jar, _ = cookiejar.New(nil)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       httpClient,
+		maxRetries: opts.MaxRetries,
+		backoff:    opts.RetryBackoff,
+		gzip:       opts.Gzip,
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	}
+	if c.backoff <= 0 {
+		c.backoff = defaultRetryBackoff
+	}
+	return c, nil
+}
+
+// Shorten returns the short URL of req.URL. A URL that is already shortened
+// fails with a *ConflictError carrying the existing short URL.
+func (c *Client) Shorten(ctx context.Context, req ShortenRequest) (string, error) {
+	var resp models.ShortenResponse
+	if err := c.do(ctx, http.MethodPost, "/api/shorten", req, http.StatusCreated, &resp); err != nil {
+		return "", err
+	}
+	return resp.Result, nil
+}
+
+// ShortenBatch shortens all URLs or none of them.
+func (c *Client) ShortenBatch(ctx context.Context, reqs []BatchShortenRequest) ([]BatchShortenResponse, error) {
+	var resp []BatchShortenResponse
+	err := c.do(ctx, http.MethodPost, "/api/shorten/batch", reqs, http.StatusCreated, &resp)
+	return resp, err
+}
+
+// Resolve returns the URL a short code, or short URL, redirects to. Like
+// any visit it counts towards click limits and statistics.
+func (c *Client) Resolve(ctx context.Context, shortURL string) (string, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/"+url.PathEscape(ShortCode(shortURL)), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		return "", readError(resp)
+	}
+	return resp.Header.Get("Location"), nil
+}
+
+// ListUserURLs returns the URLs shortened with this client's cookie.
+func (c *Client) ListUserURLs(ctx context.Context) ([]UserURL, error) {
+	resp := []UserURL{}
+	err := c.do(ctx, http.MethodGet, "/api/user/urls", nil, http.StatusOK, &resp)
+	return resp, err
+}
+
+// DeleteURLs queues short codes, or short URLs, shortened with this client's
+// cookie for deletion. The server deletes them asynchronously.
+func (c *Client) DeleteURLs(ctx context.Context, shortURLs []string) error {
+	codes := make([]string, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		codes[i] = ShortCode(shortURL)
+	}
+	return c.do(ctx, http.MethodDelete, "/api/user/urls", codes, http.StatusAccepted, nil)
+}
+
+// Stats returns the visit statistics of a short code or short URL.
+func (c *Client) Stats(ctx context.Context, shortURL string) (URLStats, error) {
+	var resp URLStats
+	path := "/api/urls/" + url.PathEscape(ShortCode(shortURL)) + "/stats"
+	err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &resp)
+	return resp, err
+}
+
+// Ping reports whether the server and its storage are available.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/ping", nil, http.StatusOK, nil)
+}
+
+// ShortCode returns the code of a short URL; bare codes are returned as is.
+func ShortCode(shortURL string) string {
+	if !strings.Contains(shortURL, "://") {
+		return shortURL
+	}
+	return shortURL[strings.LastIndex(shortURL, "/")+1:]
+}
+
+// do sends body as JSON and decodes a response with the wanted status into
+// out. 204 No Content leaves out untouched.
+func (c *Client) do(ctx context.Context, method, path string, body any, want int, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = c.encode(body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.send(ctx, method, path, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode != want:
+		return readError(resp)
+	case out == nil:
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request, retrying it as described on Client.
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+			if c.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+		}
+
+		resp, err := c.http.Do(req)
+		if attempt >= c.maxRetries || !retryable(ctx, method, resp, err) {
+			return resp, err
+		}
+
+		delay := c.retryDelay(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > maxRetryBackoff {
+					// Not worth waiting for; let the caller decide.
+					return resp, nil
+				}
+				delay = after
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+		// Jitter keeps clients that failed together from retrying together.
+		delay += time.Duration(rand.Int63n(int64(delay)/4 + 1))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay doubles the backoff with every attempt, up to maxRetryBackoff.
+// It doubles step by step because shifting by the attempt overflows.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.backoff
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+func retryable(ctx context.Context, method string, resp *http.Response, err error) bool {
+	if err != nil {
+		// A shorten request that failed in transit may have been stored.
+		return ctx.Err() == nil && method != http.MethodPost
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != http.MethodPost
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func (c *Client) encode(body any) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil || !c.gzip {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
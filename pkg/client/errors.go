@@ -0,0 +1,109 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotFound matches responses for short codes that never existed.
+	ErrNotFound = errors.New("short URL not found")
+	// ErrConflict matches responses for URLs or aliases that are already
+	// taken; errors.As with *ConflictError gives the existing short URL.
+	ErrConflict = errors.New("short URL conflict")
+	// ErrGone matches responses for short URLs that were deleted or expired.
+	ErrGone = errors.New("short URL is gone")
+)
+
+// APIError is an unsuccessful response of the shortener.
+type APIError struct {
+	StatusCode int
+	Message    string `json:"error"`
+	// Reason explains why a URL was rejected as invalid.
+	Reason string `json:"reason"`
+	// Rule names the policy rule that blocked a URL.
+	Rule string `json:"rule"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	switch {
+	case e.Reason != "":
+		msg += " (" + e.Reason + ")"
+	case e.Rule != "":
+		msg += " (rule " + e.Rule + ")"
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrGone:
+		return e.StatusCode == http.StatusGone
+	}
+	return false
+}
+
+// ConflictError reports a 409 response. ShortURL is the existing short URL
+// when the original URL was already shortened, and empty when a requested
+// alias is taken.
+type ConflictError struct {
+	APIError
+	ShortURL string `json:"short_url"`
+}
+
+func (e *ConflictError) Error() string {
+	if e.ShortURL == "" {
+		return e.APIError.Error()
+	}
+	return e.APIError.Error() + ": already shortened as " + e.ShortURL
+}
+
+func (e *ConflictError) Unwrap() error {
+	return &e.APIError
+}
+
+// GoneError reports a 410 response for a deleted or expired short URL.
+type GoneError struct {
+	APIError
+}
+
+func (e *GoneError) Unwrap() error {
+	return &e.APIError
+}
+
+// readError builds the typed error of an unsuccessful response.
+func readError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	apiErr := APIError{StatusCode: resp.StatusCode}
+	var body struct {
+		APIError
+		ShortURL string `json:"short_url"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		apiErr.Message, apiErr.Reason, apiErr.Rule = body.Message, body.Reason, body.Rule
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		return &ConflictError{APIError: apiErr, ShortURL: body.ShortURL}
+	case http.StatusGone:
+		return &GoneError{APIError: apiErr}
+	default:
+		return &apiErr
+	}
+}
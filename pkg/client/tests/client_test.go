@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hairutdin/url-shortener/internal/app/http/handlers"
+	"github.com/hairutdin/url-shortener/internal/config"
+	"github.com/hairutdin/url-shortener/internal/lib"
+	"github.com/hairutdin/url-shortener/internal/repository"
+	"github.com/hairutdin/url-shortener/internal/service"
+	"github.com/hairutdin/url-shortener/pkg/client"
+	"go.uber.org/zap"
+)
+
+// newServer serves the real router over in-memory storage.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	codes, err := lib.NewCodeGenerator(lib.CodeStrategyRandom, 8, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create code generator: %v", err)
+	}
+	logger := zap.NewNop()
+	cfg := &config.Config{BaseURL: "http://short.test", AuthSecret: "test-secret"}
+	urlService := service.NewURLService(repository.NewInMemoryStorage(), logger, cfg.BaseURL, codes,
+		service.URLNormalizer{}, nil)
+	t.Cleanup(urlService.Close)
+
+	router := handlers.SetupRouter(cfg, logger, handlers.NewBaseHandler(urlService, logger, cfg), nil, nil)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(t *testing.T, baseURL string, opts client.Options) *client.Client {
+	t.Helper()
+	c, err := client.New(baseURL, opts)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	c := newClient(t, server.URL, client.Options{Gzip: true})
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Expected ping to succeed, got %v", err)
+	}
+
+	shortURL, err := c.Shorten(ctx, client.ShortenRequest{URL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = c.Shorten(ctx, client.ShortenRequest{URL: "https://example.com/a"})
+	var conflict *client.ConflictError
+	if !errors.As(err, &conflict) || conflict.ShortURL != shortURL || !errors.Is(err, client.ErrConflict) {
+		t.Errorf("Expected a conflict with %s, got %v", shortURL, err)
+	}
+
+	batch, err := c.ShortenBatch(ctx, []client.BatchShortenRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/b", Alias: "bee"},
+	})
+	if err != nil || len(batch) != 1 || batch[0].ShortURL != "http://short.test/bee" {
+		t.Fatalf("Expected the alias to be used, got %+v, %v", batch, err)
+	}
+
+	if target, err := c.Resolve(ctx, shortURL); err != nil || target != "https://example.com/a" {
+		t.Errorf("Expected to resolve the URL, got %q, %v", target, err)
+	}
+	if _, err := c.Resolve(ctx, "missing"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	urls, err := c.ListUserURLs(ctx)
+	if err != nil || len(urls) != 2 {
+		t.Fatalf("Expected both URLs of this client, got %+v, %v", urls, err)
+	}
+	if other, _ := newClient(t, server.URL, client.Options{}).ListUserURLs(ctx); len(other) != 0 {
+		t.Errorf("Expected another client to see no URLs, got %+v", other)
+	}
+
+	if err := c.DeleteURLs(ctx, []string{"bee"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := c.Resolve(ctx, "bee")
+		var gone *client.GoneError
+		if errors.As(err, &gone) && errors.Is(err, client.ErrGone) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the deleted URL to be gone, got %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	ctx := context.Background()
+	backend := newServer(t)
+
+	target, _ := url.Parse(backend.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	var calls, failures atomic.Int32
+	failures.Store(2)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	c := newClient(t, flaky.URL, client.Options{RetryBackoff: time.Millisecond})
+	if _, err := c.Shorten(ctx, client.ShortenRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected the request to succeed after retries, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+
+	calls.Store(0)
+	failures.Store(10)
+	c = newClient(t, flaky.URL, client.Options{MaxRetries: 1, RetryBackoff: time.Millisecond})
+	err := c.Ping(ctx)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || calls.Load() != 2 {
+		t.Errorf("Expected 503 after 2 attempts, got %v after %d", err, calls.Load())
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.Ping(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled context to stop the request, got %v", err)
+	}
+}